@@ -6,3 +6,11 @@ type Attribute struct {
 	Name      string
 	Value     string
 }
+
+// InNamespace returns true if the attribute belongs to namespace, an empty namespace matches unqualified attributes
+func (a *Attribute) InNamespace(namespace string) bool {
+	if a.Namespace == nil {
+		return namespace == ""
+	}
+	return a.Namespace.Name == namespace
+}
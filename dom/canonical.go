@@ -0,0 +1,473 @@
+package dom
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// CanonicalizationMode defines the W3C canonicalization algorithm to use
+type CanonicalizationMode int
+
+const (
+	// ExclusiveC14N is Exclusive XML Canonicalization 1.0 without comments
+	ExclusiveC14N CanonicalizationMode = iota
+	// ExclusiveC14NWithComments is Exclusive XML Canonicalization 1.0 with comments
+	ExclusiveC14NWithComments
+	// C14N10 is Canonical XML 1.0 without comments
+	C14N10
+	// C14N10WithComments is Canonical XML 1.0 with comments
+	C14N10WithComments
+)
+
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+var canonicalizationAlgorithms = map[CanonicalizationMode]string{
+	ExclusiveC14N:             "http://www.w3.org/2001/10/xml-exc-c14n#",
+	ExclusiveC14NWithComments: "http://www.w3.org/2001/10/xml-exc-c14n#WithComments",
+	C14N10:                    "http://www.w3.org/TR/2001/REC-xml-c14n-20010315",
+	C14N10WithComments:        "http://www.w3.org/TR/2001/REC-xml-c14n-20010315#WithComments",
+}
+
+// Algorithm returns the algorithm URI of the mode, as used by XML signatures
+func (m CanonicalizationMode) Algorithm() string {
+	return canonicalizationAlgorithms[m]
+}
+
+// ParseCanonicalizationAlgorithm returns the mode for the given algorithm URI
+func ParseCanonicalizationAlgorithm(algorithm string) (CanonicalizationMode, bool) {
+	for mode, uri := range canonicalizationAlgorithms {
+		if uri == algorithm {
+			return mode, true
+		}
+	}
+	return 0, false
+}
+
+func (m CanonicalizationMode) exclusive() bool {
+	return m == ExclusiveC14N || m == ExclusiveC14NWithComments
+}
+
+func (m CanonicalizationMode) comments() bool {
+	return m == ExclusiveC14NWithComments || m == C14N10WithComments
+}
+
+// CanonicalMatcher selects elements for CanonicalizeXML by their namespace URI, local name and attributes
+// (the attribute names carry the resolved namespace URI in Name.Space)
+type CanonicalMatcher func(namespace string, name string, attributes []xml.Attr) bool
+
+// canonicalNode is the exact representation of an element needed for canonicalization,
+// built either from a Node or from the raw XML (keeping all text and comments in order)
+type canonicalNode struct {
+	parent       *canonicalNode
+	prefix       string
+	namespace    string
+	name         string
+	attributes   []canonicalAttribute
+	declarations map[string]string
+	children     []interface{}
+}
+
+type canonicalAttribute struct {
+	prefix    string
+	namespace string
+	name      string
+	value     string
+}
+
+type canonicalText string
+
+type canonicalComment string
+
+type canonicalProcInst xml.ProcInst
+
+// canonicalizer writes canonical XML for a mode
+type canonicalizer struct {
+	mode              CanonicalizationMode
+	inclusivePrefixes []string
+	exclude           CanonicalMatcher
+}
+
+// Canonicalize returns the canonical form of this Node and its children using the given mode.
+// inclusivePrefixes is the InclusiveNamespaces PrefixList of exclusive canonicalization ("#default" for the
+// default namespace), it is ignored for C14N10. As our DOM keeps no comments, the WithComments modes only
+// differ from their counterparts in CanonicalizeXML
+func (n *Node) Canonicalize(mode CanonicalizationMode, inclusivePrefixes []string) ([]byte, error) {
+	if _, exists := canonicalizationAlgorithms[mode]; !exists {
+		return nil, fmt.Errorf("Canonicalize(): unsupported canonicalization mode [%d]", mode)
+	}
+
+	// rebuild the ancestors as namespace context
+	var context *canonicalNode
+	ancestors := []*Node{}
+	for parent := n.Parent; parent != nil; parent = parent.Parent {
+		ancestors = append([]*Node{parent}, ancestors...)
+	}
+	for _, ancestor := range ancestors {
+		context = newCanonicalContext(ancestor, context)
+	}
+
+	buffer := &bytes.Buffer{}
+	c := &canonicalizer{mode: mode, inclusivePrefixes: inclusivePrefixes}
+	c.write(buffer, newCanonicalNode(n, context), map[string]string{}, true)
+	return buffer.Bytes(), nil
+}
+
+// CanonicalizeXML canonicalizes all elements of the raw XML document selected by match (in document order).
+// Unlike Node.Canonicalize it works on the exact input, including whitespace between elements and comments
+func CanonicalizeXML(raw []byte, mode CanonicalizationMode, inclusivePrefixes []string, match CanonicalMatcher) ([][]byte, error) {
+	return CanonicalizeXMLExcluding(raw, mode, inclusivePrefixes, match, nil)
+}
+
+// CanonicalizeXMLExcluding works like CanonicalizeXML, omitting the descendants selected by exclude (along with their
+// subtrees) from the output, e.g. the ds:Signature removed by the enveloped-signature transform of XML DSig
+func CanonicalizeXMLExcluding(raw []byte, mode CanonicalizationMode, inclusivePrefixes []string, match CanonicalMatcher, exclude CanonicalMatcher) ([][]byte, error) {
+	if _, exists := canonicalizationAlgorithms[mode]; !exists {
+		return nil, fmt.Errorf("CanonicalizeXML(): unsupported canonicalization mode [%d]", mode)
+	}
+
+	roots, err := parseCanonical(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &canonicalizer{mode: mode, inclusivePrefixes: inclusivePrefixes, exclude: exclude}
+	result := [][]byte{}
+	var walk func(node *canonicalNode)
+	walk = func(node *canonicalNode) {
+		if match(node.namespace, node.name, node.xmlAttributes()) {
+			buffer := &bytes.Buffer{}
+			c.write(buffer, node, map[string]string{}, true)
+			result = append(result, buffer.Bytes())
+		}
+		for _, child := range node.children {
+			if element, ok := child.(*canonicalNode); ok {
+				walk(element)
+			}
+		}
+	}
+	for _, root := range roots {
+		walk(root)
+	}
+	return result, nil
+}
+
+// newCanonicalContext builds a canonicalNode carrying only the namespace declarations of n
+func newCanonicalContext(n *Node, parent *canonicalNode) *canonicalNode {
+	context := &canonicalNode{
+		parent:       parent,
+		declarations: map[string]string{},
+	}
+	for _, ns := range n.NamespaceMapping {
+		// the xml prefix is bound by definition and never declared
+		if ns.Name != xmlNamespace {
+			context.declarations[ns.Abbreviation] = ns.Name
+		}
+	}
+
+	// xml:* attributes are inherited by C14N 1.0
+	for _, attr := range n.Attributes {
+		if attr.Namespace != nil && attr.Namespace.Name == xmlNamespace {
+			context.attributes = append(context.attributes, canonicalAttribute{
+				prefix: "xml", namespace: xmlNamespace, name: attr.Name, value: attr.Value,
+			})
+		}
+	}
+	return context
+}
+
+func newCanonicalNode(n *Node, parent *canonicalNode) *canonicalNode {
+	node := newCanonicalContext(n, parent)
+
+	// element name; nodes may also carry their prefix in the name (e.g. "s:Envelope")
+	if n.Namespace != nil {
+		node.prefix, node.name, node.namespace = n.Namespace.Abbreviation, n.Name, n.Namespace.Name
+	} else {
+		node.prefix, node.name = SplitFQName(n.Name)
+		node.namespace = node.lookupPrefix(node.prefix)
+	}
+
+	node.attributes = nil
+	for _, attr := range n.Attributes {
+		attribute := canonicalAttribute{name: attr.Name, value: attr.Value}
+		if attr.Namespace != nil && attr.Namespace.Name == xmlNamespace {
+			attribute.prefix, attribute.namespace = "xml", xmlNamespace
+		} else if attr.Namespace != nil && attr.Namespace.Abbreviation != "" {
+			attribute.prefix, attribute.namespace = attr.Namespace.Abbreviation, attr.Namespace.Name
+		}
+		node.attributes = append(node.attributes, attribute)
+	}
+
+	// our DOM keeps the text before the children, just like Node.XML() does
+	if n.Value.value != nil {
+		node.children = append(node.children, canonicalText(n.String()))
+	}
+	for _, child := range n.Children.All() {
+		node.children = append(node.children, newCanonicalNode(child, node))
+	}
+	return node
+}
+
+// parseCanonical reads the raw XML into canonicalNodes, resolving namespace prefixes on its own
+// as the xml.Decoder drops them
+func parseCanonical(raw []byte) ([]*canonicalNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(raw))
+	roots := []*canonicalNode{}
+	var current *canonicalNode
+
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &canonicalNode{
+				parent:       current,
+				prefix:       t.Name.Space,
+				name:         t.Name.Local,
+				declarations: map[string]string{},
+			}
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" {
+					node.declarations[attr.Name.Local] = attr.Value
+				} else if attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+					node.declarations[""] = attr.Value
+				}
+			}
+			node.namespace = node.lookupPrefix(node.prefix)
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+					continue
+				}
+				attribute := canonicalAttribute{prefix: attr.Name.Space, name: attr.Name.Local, value: attr.Value}
+				if attribute.prefix != "" {
+					attribute.namespace = node.lookupPrefix(attribute.prefix)
+				}
+				node.attributes = append(node.attributes, attribute)
+			}
+
+			if current == nil {
+				roots = append(roots, node)
+			} else {
+				current.children = append(current.children, node)
+			}
+			current = node
+
+		case xml.EndElement:
+			if current == nil {
+				return nil, fmt.Errorf("CanonicalizeXML(): unexpected end element [%s]", t.Name.Local)
+			}
+			current = current.parent
+
+		case xml.CharData:
+			if current != nil {
+				current.children = append(current.children, canonicalText(t))
+			}
+
+		case xml.Comment:
+			if current != nil {
+				current.children = append(current.children, canonicalComment(t))
+			}
+
+		case xml.ProcInst:
+			if current != nil {
+				current.children = append(current.children, canonicalProcInst(t.Copy()))
+			}
+		}
+	}
+
+	if current != nil {
+		return nil, fmt.Errorf("CanonicalizeXML(): unexpected end of document in element [%s]", current.name)
+	}
+	return roots, nil
+}
+
+// lookupPrefix returns the namespace URI bound to prefix in the scope of this node
+func (c *canonicalNode) lookupPrefix(prefix string) string {
+	if prefix == "xml" {
+		return xmlNamespace
+	}
+	for node := c; node != nil; node = node.parent {
+		if namespace, exists := node.declarations[prefix]; exists {
+			return namespace
+		}
+	}
+	return ""
+}
+
+func (c *canonicalNode) xmlAttributes() []xml.Attr {
+	result := make([]xml.Attr, len(c.attributes))
+	for i, attr := range c.attributes {
+		result[i] = xml.Attr{Name: xml.Name{Space: attr.namespace, Local: attr.name}, Value: attr.value}
+	}
+	return result
+}
+
+// inScope returns all namespace declarations in scope of this node, including the ones utilized
+// by the node itself (our DOM may reference namespaces not declared by any ancestor)
+func (c *canonicalNode) inScope() map[string]string {
+	result := map[string]string{}
+	for node := c; node != nil; node = node.parent {
+		for prefix, namespace := range node.declarations {
+			if _, exists := result[prefix]; !exists {
+				result[prefix] = namespace
+			}
+		}
+	}
+	for prefix, namespace := range c.utilized() {
+		result[prefix] = namespace
+	}
+	return result
+}
+
+// utilized returns the namespaces visibly utilized by the node: its own and those of its attributes
+func (c *canonicalNode) utilized() map[string]string {
+	result := map[string]string{c.prefix: c.namespace}
+	for _, attr := range c.attributes {
+		if attr.prefix != "" {
+			result[attr.prefix] = attr.namespace
+		}
+	}
+	return result
+}
+
+// namespaces returns the namespace candidates to render for the node
+func (c *canonicalizer) namespaces(node *canonicalNode) map[string]string {
+	if !c.mode.exclusive() {
+		return node.inScope()
+	}
+
+	// visibly utilized namespaces, and the ones listed explicitly
+	result := node.utilized()
+	for _, prefix := range c.inclusivePrefixes {
+		if prefix == "#default" {
+			prefix = ""
+		}
+		if namespace := node.lookupPrefix(prefix); namespace != "" {
+			result[prefix] = namespace
+		}
+	}
+	return result
+}
+
+// write writes the canonical form of node; rendered holds the namespace declarations already output by the ancestors
+func (c *canonicalizer) write(buffer *bytes.Buffer, node *canonicalNode, rendered map[string]string, apex bool) {
+	candidates := c.namespaces(node)
+	delete(candidates, "xml")
+
+	prefixes := []string{}
+	nextRendered := map[string]string{}
+	for prefix, namespace := range rendered {
+		nextRendered[prefix] = namespace
+	}
+	for prefix, namespace := range candidates {
+		previous, exists := rendered[prefix]
+		if (exists && previous == namespace) || (!exists && namespace == "") {
+			continue
+		}
+		prefixes = append(prefixes, prefix)
+		nextRendered[prefix] = namespace
+	}
+	sort.Strings(prefixes)
+
+	attributes := append([]canonicalAttribute{}, node.attributes...)
+
+	// C14N 1.0 lets the apex inherit xml:* attributes of ancestors outside of the canonicalized subtree
+	if apex && !c.mode.exclusive() {
+		inherited := map[string]bool{}
+		for _, attr := range attributes {
+			if attr.namespace == xmlNamespace {
+				inherited[attr.name] = true
+			}
+		}
+		for ancestor := node.parent; ancestor != nil; ancestor = ancestor.parent {
+			for _, attr := range ancestor.attributes {
+				if attr.namespace == xmlNamespace && !inherited[attr.name] {
+					inherited[attr.name] = true
+					attributes = append(attributes, attr)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(attributes, func(i, j int) bool {
+		if attributes[i].namespace != attributes[j].namespace {
+			return attributes[i].namespace < attributes[j].namespace
+		}
+		return attributes[i].name < attributes[j].name
+	})
+
+	// start tag
+	qualifiedName := node.name
+	if node.prefix != "" {
+		qualifiedName = node.prefix + ":" + node.name
+	}
+	buffer.WriteString("<" + qualifiedName)
+	for _, prefix := range prefixes {
+		if prefix == "" {
+			buffer.WriteString(" xmlns=\"")
+		} else {
+			buffer.WriteString(" xmlns:" + prefix + "=\"")
+		}
+		buffer.WriteString(canonicalAttributeEscaper.Replace(candidates[prefix]) + "\"")
+	}
+	for _, attr := range attributes {
+		buffer.WriteString(" ")
+		if attr.prefix != "" {
+			buffer.WriteString(attr.prefix + ":")
+		}
+		buffer.WriteString(attr.name + "=\"" + canonicalAttributeEscaper.Replace(attr.value) + "\"")
+	}
+	buffer.WriteString(">")
+
+	// content
+	for _, child := range node.children {
+		switch t := child.(type) {
+		case *canonicalNode:
+			if c.exclude != nil && c.exclude(t.namespace, t.name, t.xmlAttributes()) {
+				continue
+			}
+			c.write(buffer, t, nextRendered, false)
+		case canonicalText:
+			buffer.WriteString(canonicalTextEscaper.Replace(string(t)))
+		case canonicalComment:
+			if c.mode.comments() {
+				buffer.WriteString("<!--" + string(t) + "-->")
+			}
+		case canonicalProcInst:
+			buffer.WriteString("<?" + t.Target)
+			if len(t.Inst) > 0 {
+				buffer.WriteString(" " + string(t.Inst))
+			}
+			buffer.WriteString("?>")
+		}
+	}
+
+	// end tag, canonical XML never uses empty element tags
+	buffer.WriteString("</" + qualifiedName + ">")
+}
+
+var (
+	canonicalTextEscaper = strings.NewReplacer(
+		"&", "&amp;",
+		"<", "&lt;",
+		">", "&gt;",
+		"\r", "&#xD;",
+	)
+	canonicalAttributeEscaper = strings.NewReplacer(
+		"&", "&amp;",
+		"<", "&lt;",
+		"\"", "&quot;",
+		"\t", "&#x9;",
+		"\n", "&#xA;",
+		"\r", "&#xD;",
+	)
+)
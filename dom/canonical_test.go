@@ -0,0 +1,190 @@
+package dom
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+// W3C Canonical XML 1.0, section 3.2 (whitespace in document content)
+const c14nWhitespace = `<doc>
+   <clean>   </clean>
+   <dirty>   A   B   </dirty>
+   <mixed>
+      A
+      <clean>   </clean>
+      B
+      <dirty>   A   B   </dirty>
+      C
+   </mixed>
+</doc>`
+
+// W3C Canonical XML 1.0, section 3.3 (start and end tags), without the DTD defaulting an attribute of e9
+const c14nStartEndTags = `<doc>
+   <e1   />
+   <e2   ></e2>
+   <e3   name = "elem3"   id="elem3"   />
+   <e4   name="elem4"   id="elem4"   ></e4>
+   <e5 a:attr="out" b:attr="sorted" attr2="all" attr="I'm"
+      xmlns:b="http://www.ietf.org"
+      xmlns:a="http://www.w3.org"
+      xmlns="http://example.org"/>
+   <e6 xmlns="" xmlns:a="http://www.w3.org">
+      <e7 xmlns="http://www.ietf.org">
+         <e8 xmlns="" xmlns:a="http://www.w3.org">
+            <e9 xmlns="" xmlns:a="http://www.ietf.org"/>
+         </e8>
+      </e7>
+   </e6>
+</doc>`
+
+const c14nStartEndTagsCanonical = `<doc>
+   <e1></e1>
+   <e2></e2>
+   <e3 id="elem3" name="elem3"></e3>
+   <e4 id="elem4" name="elem4"></e4>
+   <e5 xmlns="http://example.org" xmlns:a="http://www.w3.org" xmlns:b="http://www.ietf.org" attr="I'm" attr2="all" b:attr="sorted" a:attr="out"></e5>
+   <e6 xmlns:a="http://www.w3.org">
+      <e7 xmlns="http://www.ietf.org">
+         <e8 xmlns="">
+            <e9 xmlns:a="http://www.ietf.org"></e9>
+         </e8>
+      </e7>
+   </e6>
+</doc>`
+
+// W3C Canonical XML 1.0, section 3.4 (character modifications and character references), without the DTD
+const c14nCharacters = `<doc>
+   <text>First line&#x0d;&#10;Second line</text>
+   <value>&#x32;</value>
+   <compute><![CDATA[value>"0" && value<"10" ?"valid":"error"]]></compute>
+   <compute expr='value>"0" &amp;&amp; value&lt;"10" ?"valid":"error"'>valid</compute>
+   <norm attr=' &apos;   &#x20;&#13;&#xa;&#9;   &apos; '/>
+</doc>`
+
+const c14nCharactersCanonical = "<doc>\n" +
+	"   <text>First line&#xD;\nSecond line</text>\n" +
+	"   <value>2</value>\n" +
+	`   <compute>value&gt;"0" &amp;&amp; value&lt;"10" ?"valid":"error"</compute>` + "\n" +
+	`   <compute expr="value>&quot;0&quot; &amp;&amp; value&lt;&quot;10&quot; ?&quot;valid&quot;:&quot;error&quot;">valid</compute>` + "\n" +
+	`   <norm attr=" '    &#xD;&#xA;&#x9;   ' "></norm>` + "\n" +
+	"</doc>"
+
+// W3C Exclusive XML Canonicalization 1.0, section 2.2: the same element in two different contexts
+const excC14NFirstContext = `<n0:local xmlns:n0="foo:bar" xmlns:n3="ftp://example.org">
+  <n1:elem2 xmlns:n1="http://example.net" xml:lang="en">
+    <n3:stuff xmlns:n3="ftp://example.org"/>
+  </n1:elem2>
+</n0:local>`
+
+const excC14NSecondContext = `<n2:pdu xmlns:n1="http://example.com"
+           xmlns:n2="http://foo.example"
+           xml:lang="fr"
+           xml:space="retain">
+  <n1:elem2 xmlns:n1="http://example.net" xml:lang="en">
+    <n3:stuff xmlns:n3="ftp://example.org"/>
+  </n1:elem2>
+</n2:pdu>`
+
+const excC14NElem2 = `<n1:elem2 xmlns:n1="http://example.net" xml:lang="en">
+    <n3:stuff xmlns:n3="ftp://example.org"></n3:stuff>
+  </n1:elem2>`
+
+const c14nComments = `<doc><!-- comment --><e1 a="1"/><?pi data?></doc>`
+
+func canonicalTestMatcher(local string) CanonicalMatcher {
+	return func(namespace string, name string, attributes []xml.Attr) bool {
+		return name == local
+	}
+}
+
+func TestCanonicalizeXML(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		element  string
+		mode     CanonicalizationMode
+		prefixes []string
+		expected string
+	}{
+		{"whitespace", c14nWhitespace, "doc", C14N10, nil, c14nWhitespace},
+		{"start and end tags", c14nStartEndTags, "doc", C14N10, nil, c14nStartEndTagsCanonical},
+		{"characters", c14nCharacters, "doc", C14N10, nil, c14nCharactersCanonical},
+		{"inclusive first context", excC14NFirstContext, "elem2", C14N10, nil,
+			`<n1:elem2 xmlns:n0="foo:bar" xmlns:n1="http://example.net" xmlns:n3="ftp://example.org" xml:lang="en">
+    <n3:stuff></n3:stuff>
+  </n1:elem2>`},
+		{"inclusive second context", excC14NSecondContext, "elem2", C14N10, nil,
+			`<n1:elem2 xmlns:n1="http://example.net" xmlns:n2="http://foo.example" xml:lang="en" xml:space="retain">
+    <n3:stuff xmlns:n3="ftp://example.org"></n3:stuff>
+  </n1:elem2>`},
+		{"exclusive first context", excC14NFirstContext, "elem2", ExclusiveC14N, nil, excC14NElem2},
+		{"exclusive second context", excC14NSecondContext, "elem2", ExclusiveC14N, nil, excC14NElem2},
+		{"exclusive inclusive prefixes", excC14NFirstContext, "elem2", ExclusiveC14N, []string{"n0", "n2"},
+			`<n1:elem2 xmlns:n0="foo:bar" xmlns:n1="http://example.net" xml:lang="en">
+    <n3:stuff xmlns:n3="ftp://example.org"></n3:stuff>
+  </n1:elem2>`},
+		{"exclusive default namespace", `<a xmlns="urn:a"><b><c xmlns:p="urn:p"/></b></a>`, "b", ExclusiveC14N, []string{"#default"},
+			`<b xmlns="urn:a"><c></c></b>`},
+		{"without comments", c14nComments, "doc", C14N10, nil, `<doc><e1 a="1"></e1><?pi data?></doc>`},
+		{"with comments", c14nComments, "doc", C14N10WithComments, nil, `<doc><!-- comment --><e1 a="1"></e1><?pi data?></doc>`},
+		{"exclusive with comments", c14nComments, "doc", ExclusiveC14NWithComments, nil, `<doc><!-- comment --><e1 a="1"></e1><?pi data?></doc>`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := CanonicalizeXML([]byte(test.input), test.mode, test.prefixes, canonicalTestMatcher(test.element))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result) != 1 {
+				t.Fatalf("expected one element, got %d", len(result))
+			}
+			if string(result[0]) != test.expected {
+				t.Fatalf("expected\n%s\ngot\n%s", test.expected, result[0])
+			}
+		})
+	}
+}
+
+func TestCanonicalizeXMLExcluding(t *testing.T) {
+	input := `<a xmlns:s="urn:s"><b>1</b><s:Signature><c/></s:Signature><b>2</b></a>`
+	result, err := CanonicalizeXMLExcluding([]byte(input), ExclusiveC14N, nil, canonicalTestMatcher("a"),
+		func(namespace string, name string, attributes []xml.Attr) bool {
+			return namespace == "urn:s" && name == "Signature"
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `<a><b>1</b><b>2</b></a>`; string(result[0]) != expected {
+		t.Fatalf("expected %s, got %s", expected, result[0])
+	}
+}
+
+func TestNodeCanonicalize(t *testing.T) {
+	document, err := Parse([]byte(`<n2:pdu xmlns:n1="http://example.com" xmlns:n2="http://foo.example" xml:lang="fr" xml:space="retain">` +
+		`<n1:elem2 xmlns:n1="http://example.net" xml:lang="en"><n3:stuff xmlns:n3="ftp://example.org"/></n1:elem2></n2:pdu>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	elem2 := document.Root.XPath("elem2").First()
+
+	tests := []struct {
+		mode     CanonicalizationMode
+		expected string
+	}{
+		{ExclusiveC14N, `<n1:elem2 xmlns:n1="http://example.net" xml:lang="en"><n3:stuff xmlns:n3="ftp://example.org"></n3:stuff></n1:elem2>`},
+		{C14N10, `<n1:elem2 xmlns:n1="http://example.net" xmlns:n2="http://foo.example" xml:lang="en" xml:space="retain"><n3:stuff xmlns:n3="ftp://example.org"></n3:stuff></n1:elem2>`},
+	}
+	for _, test := range tests {
+		result, err := elem2.Canonicalize(test.mode, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(result) != test.expected {
+			t.Fatalf("%s: expected\n%s\ngot\n%s", test.mode.Algorithm(), test.expected, result)
+		}
+	}
+
+	if _, err := elem2.Canonicalize(CanonicalizationMode(-1), nil); err == nil {
+		t.Fatal("expected an error for an unknown mode")
+	}
+}
@@ -1,14 +1,54 @@
 package dom
 
 import (
+	"bytes"
 	"encoding/xml"
 	"fmt"
+	"strings"
 )
 
 // Document defines the topmost DOM entry
 type Document struct {
 	Root         *Node
 	nextNSAbbrev int
+
+	// raw input while parsing, to tell CDATA sections apart
+	raw           []byte
+	preserveCDATA bool
+}
+
+// ParseOption configures Parse
+type ParseOption func(d *Document)
+
+// PreserveCDATA keeps text consisting of CDATA sections as CDATA values, so it is written as CDATA again
+func PreserveCDATA() ParseOption {
+	return func(d *Document) {
+		d.preserveCDATA = true
+	}
+}
+
+// Parse decodes the raw XML into a new Document
+func Parse(raw []byte, options ...ParseOption) (*Document, error) {
+	document := &Document{}
+	for _, option := range options {
+		option(document)
+	}
+	if document.preserveCDATA {
+		document.raw = raw
+		defer func() { document.raw = nil }()
+	}
+	if err := xml.NewDecoder(bytes.NewReader(raw)).Decode(document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// isCDATA checks if the input between the offsets is a CDATA section
+func (d *Document) isCDATA(start int64, end int64) bool {
+	if d.raw == nil || start < 0 || end > int64(len(d.raw)) || start > end {
+		return false
+	}
+	return bytes.HasPrefix(d.raw[start:end], []byte("<![CDATA["))
 }
 
 // NewDocument returns a new document ready to use
@@ -21,7 +61,8 @@ func NewDocument(rootNode string) *Document {
 	return newDocument
 }
 
-// Convert takes the argument and converts it into our DOM
+// Convert takes the argument and converts it into our DOM, map keys starting with "@" set attributes
+// and "#text" sets the value of a node with attributes
 func Convert(name string, in interface{}) *Document {
 	result := NewDocument(name)
 	result.Root.convert(name, in, result, nil)
@@ -36,22 +77,22 @@ func (n *Node) convert(name string, in interface{}, document *Document, parent *
 	switch t := in.(type) {
 	case map[string]interface{}:
 		for k, v := range t {
-			n.NewChildren(k, "").convert(k, v, document, n)
+			n.convertEntry(k, v, document)
 		}
 	case []map[string]interface{}:
 		for _, sub := range t {
 			for k, v := range sub {
-				n.NewChildren(k, "").convert(k, v, document, n)
+				n.convertEntry(k, v, document)
 			}
 		}
 	case Map:
 		for k, v := range t {
-			n.NewChildren(k, "").convert(k, v, document, n)
+			n.convertEntry(k, v, document)
 		}
 	case []Map:
 		for _, sub := range t {
 			for k, v := range sub {
-				n.NewChildren(k, "").convert(k, v, document, n)
+				n.convertEntry(k, v, document)
 			}
 		}
 	default:
@@ -59,6 +100,18 @@ func (n *Node) convert(name string, in interface{}, document *Document, parent *
 	}
 }
 
+// convertEntry converts a key-value pair into a child, keys starting with "@" into an attribute and "#text" into the value
+func (n *Node) convertEntry(k string, v interface{}, document *Document) {
+	switch {
+	case strings.HasPrefix(k, "@"):
+		n.SetAttribute("", k[1:], fmt.Sprint(v))
+	case k == textKey:
+		n.Value.value = v
+	default:
+		n.NewChildren(k, "").convert(k, v, document, n)
+	}
+}
+
 // XML outputs the Node as a XML entity
 func (d *Document) XML() string {
 	return xml.Header + d.Root.XML()
@@ -77,6 +130,15 @@ func (d *Document) XPath(xpath string, arguments ...interface{}) *NodeList {
 	return d.Root.XPath(xpath, arguments...)
 }
 
+// XPathNS resolves the given XPath to a list of Nodes matching names by namespace, see Node.XPathNS.
+// Relative paths start at the document node
+func (d *Document) XPathNS(xpath string, namespaces map[string]string, arguments ...interface{}) *NodeList {
+	if namespaces == nil {
+		namespaces = map[string]string{}
+	}
+	return d.Root.selectXPath(xpath, namespaces, arguments, true)
+}
+
 // NewNSAbbrev returns a new abbreviation to use for a namespace
 func (d *Document) NewNSAbbrev() string {
 	d.nextNSAbbrev++
@@ -2,3 +2,58 @@ package dom
 
 // Map is a default shortcut for writing a generic key-value map
 type Map map[string]interface{}
+
+const (
+	// textKey is the Map key of the value of a node with attributes
+	textKey = "#text"
+
+	xsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+// ToMap converts the attributes and children of the Node into a Map, the inverse of Convert.
+// Leaf children become their string value, repeated children become a []interface{}.
+// Attributes become "@name" keys (xsi:nil and xsi:type are left out), so leaf children with attributes
+// become a Map holding their value as "#text"
+func (n *Node) ToMap() Map {
+	result := Map{}
+	for _, attr := range n.mapAttributes() {
+		result["@"+attr.Name] = attr.Value
+	}
+
+	for _, child := range n.Children.All() {
+		var value interface{}
+		switch {
+		case child.Children.Len() > 0:
+			value = child.ToMap()
+		case len(child.mapAttributes()) > 0:
+			mapping := child.ToMap()
+			if !child.IsNil() {
+				mapping[textKey] = child.String()
+			}
+			value = mapping
+		default:
+			value = child.String()
+		}
+
+		switch existing := result[child.Name].(type) {
+		case nil:
+			result[child.Name] = value
+		case []interface{}:
+			result[child.Name] = append(existing, value)
+		default:
+			result[child.Name] = []interface{}{existing, value}
+		}
+	}
+	return result
+}
+
+// mapAttributes returns the attributes carrying data, leaving out those of XML schema instance
+func (n *Node) mapAttributes() []*Attribute {
+	result := []*Attribute{}
+	for _, attr := range n.Attributes {
+		if attr.Namespace == nil || attr.Namespace.Name != xsiNamespace {
+			result = append(result, attr)
+		}
+	}
+	return result
+}
@@ -1,9 +1,16 @@
 package dom
 
 import (
+	"errors"
 	"strings"
 )
 
+// ErrUnknownNamespace is returned when a namespace abbreviation cannot be resolved
+var ErrUnknownNamespace = errors.New("unknown namespace abbreviation")
+
+// xmlNamespaceBinding is the xml prefix, bound by definition and never declared
+var xmlNamespaceBinding = &Namespace{Name: xmlNamespace, Abbreviation: "xml"}
+
 // Namespace defines a Namespace entity
 type Namespace struct {
 	Name         string
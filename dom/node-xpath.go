@@ -2,21 +2,37 @@ package dom
 
 import (
 	"fmt"
-	"regexp"
-	"strconv"
 	"strings"
 )
 
+// arguments substituted into an expression are enclosed by these private use characters if they need escaping,
+// so only they are unescaped again
+const (
+	xPathArgumentStart = "\uE000"
+	xPathArgumentEnd   = "\uE001"
+)
+
 var (
-	xPathOutputDebug        = false
-	xPathIndexCondition     = regexp.MustCompile(`^[0-9]+$`)
-	xPathAttributeCondition = regexp.MustCompile(`^@(.+?)(='(.+)')?$`)
-	xPathLastCondition      = regexp.MustCompile(`^last\(\)(-([0-9]+))?$`)
-	xPathEscaper            = strings.NewReplacer(
+	xPathOutputDebug = false
+	xPathEscaper     = strings.NewReplacer(
+		`%`, `%25`,
 		`'`, `%27`,
+		`"`, `%22`,
 		`/`, `%2F`,
 		`[`, `%5B`,
 		`]`, `%5D`,
+		xPathArgumentStart, `%E0`,
+		xPathArgumentEnd, `%E1`,
+	)
+	xPathUnescaper = strings.NewReplacer(
+		`%25`, `%`,
+		`%27`, `'`,
+		`%22`, `"`,
+		`%2F`, `/`,
+		`%5B`, `[`,
+		`%5D`, `]`,
+		`%E0`, xPathArgumentStart,
+		`%E1`, xPathArgumentEnd,
 	)
 )
 
@@ -58,8 +74,39 @@ func (n *Node) GetXPathName(name string) string {
 	return fmt.Sprintf("%s[%d]", name, index+1)
 }
 
-// XPath resolves the given XPath to a list of Nodes
+// XPath resolves the given XPath expression to a list of Nodes in document order; it panics if the
+// expression is invalid or does not select nodes.
+// All XPath 1.0 axes except namespace:: are supported, as well as the node tests node(), text(), comment()
+// and processing-instruction() (our DOM keeps neither comments nor processing instructions).
+// Attributes and text are returned as detached Nodes carrying the value, the document node as the root element.
+// Names are matched by their local or prefixed name, and a relative path on the root element may start with its name
 func (n *Node) XPath(xpath string, arguments ...interface{}) *NodeList {
+	return n.selectXPath(xpath, nil, arguments, false)
+}
+
+// XPathNS resolves the given XPath expression like XPath, but matches names by namespace: prefixes of
+// name tests are bound by namespaces (prefix -> URI), unprefixed names select nodes without namespace
+func (n *Node) XPathNS(xpath string, namespaces map[string]string, arguments ...interface{}) *NodeList {
+	if namespaces == nil {
+		namespaces = map[string]string{}
+	}
+	return n.selectXPath(xpath, namespaces, arguments, false)
+}
+
+// Eval evaluates the given XPath expression, returning a string, float64, bool or *NodeList
+func (n *Node) Eval(xpath string, arguments ...interface{}) (interface{}, error) {
+	return n.evalXPath(xpath, nil, arguments, false)
+}
+
+// EvalNS evaluates the given XPath expression like Eval, matching names by namespace like XPathNS
+func (n *Node) EvalNS(xpath string, namespaces map[string]string, arguments ...interface{}) (interface{}, error) {
+	if namespaces == nil {
+		namespaces = map[string]string{}
+	}
+	return n.evalXPath(xpath, namespaces, arguments, false)
+}
+
+func (n *Node) selectXPath(xpath string, namespaces map[string]string, arguments []interface{}, fromDocument bool) *NodeList {
 	if !n.Exists {
 		return &NodeList{}
 	}
@@ -69,72 +116,72 @@ func (n *Node) XPath(xpath string, arguments ...interface{}) *NodeList {
 		xpath = n.xPathPrintf(xpath, arguments...)
 	}
 
-	// get next xpath token
-	next, xpath := n.nextXPathToken(xpath)
+	compiled, err := xPathCompiled.compile(xpath, namespaces)
+	if err == nil && !compiled.SelectsNodes() {
+		err = fmt.Errorf("XPath(): expression [%s] does not select nodes, use Eval()", xpath)
+	}
+	if err != nil {
+		panic(err)
+	}
 
-	xPathDebug("[xpath] self=%s, next=%s, xpath=%s\n", n.Name, next, xpath)
+	xPathDebug("[xpath] self=%s, xpath=%s\n", n.Name, xpath)
+	return n.evaluateXPath(compiled.expr, namespaces == nil, fromDocument).(xPathItems).nodeList()
+}
 
-	// we are done
-	if next == "" {
-		return &NodeList{nodes: []*Node{n}}
+func (n *Node) evalXPath(xpath string, namespaces map[string]string, arguments []interface{}, fromDocument bool) (interface{}, error) {
+	if len(arguments) > 0 {
+		xpath = n.xPathPrintf(xpath, arguments...)
 	}
 
-	// goto root
-	if next == "/" {
-		return n.Document.XPath(xpath)
+	compiled, err := xPathCompiled.compile(xpath, namespaces)
+	if err != nil {
+		return nil, err
 	}
 
-	// complicated
-	if next == "//" {
-		panic(fmt.Errorf("XPath(): // is not yet supported"))
+	result := n.evaluateXPath(compiled.expr, namespaces == nil, fromDocument)
+	if items, ok := result.(xPathItems); ok {
+		return items.nodeList(), nil
 	}
+	return result, nil
+}
 
-	// why?
-	if next == "." || (next == n.Name && n.Parent == nil) {
-		return n.XPath(xpath)
+// evaluateXPath evaluates the expression with this Node (or the document node) as context
+func (n *Node) evaluateXPath(expr xPathExpr, rootByName bool, fromDocument bool) interface{} {
+	context := xPathItem{kind: xPathItemElement, node: n}
+	if location, ok := expr.(*xPathLocation); fromDocument ||
+		(rootByName && ok && n.Parent == nil && location.startsWithName(n.Name)) {
+		context = context.document()
 	}
+	return expr.evaluate(xPathContext{item: context, position: 1, size: 1})
+}
 
-	// why??
-	if next == ".." {
-		if n.Parent != nil {
-			return n.Parent.XPath(xpath)
-		}
-		panic(fmt.Errorf("XPath(): element has no parent"))
+// xPathEscape escapes an argument substituted into an expression; arguments without special characters
+// (like names and numbers) are substituted as they are
+func (n *Node) xPathEscape(in string) string {
+	if !strings.ContainsAny(in, `%'"/[]`+xPathArgumentStart+xPathArgumentEnd) {
+		return in
 	}
+	return xPathArgumentStart + xPathEscaper.Replace(in) + xPathArgumentEnd
+}
 
-	name, condition := n.splitXPathToken(next)
-
-	// name comparison
-	result := &NodeList{}
-	nodes := n.Children.All()
-	count := len(nodes)
-
-	namedIndex := map[string]int{}
-
-	for _, child := range nodes {
-		// compare name
-		if n.xPathEscape(child.Name) == name || name == "*" {
-			if _, exists := namedIndex[child.Name]; !exists {
-				namedIndex[child.Name] = 0
-			}
-			namedIndex[child.Name]++
-
-			// condition test
-			if !n.checkXPathCondition(condition, child, namedIndex[child.Name], count) {
-				continue
-			}
-
-			// append result
-			childResult := child.XPath(xpath)
-			result.AppendList(childResult)
+// xPathUnescape reverts the escaping of the arguments contained in a name or literal, leaving the rest of it untouched
+func xPathUnescape(in string) string {
+	result := strings.Builder{}
+	for {
+		start := strings.Index(in, xPathArgumentStart)
+		if start == -1 {
+			break
 		}
+		end := strings.Index(in[start:], xPathArgumentEnd)
+		if end == -1 {
+			break
+		}
+		result.WriteString(in[:start])
+		result.WriteString(xPathUnescaper.Replace(in[start+len(xPathArgumentStart) : start+end]))
+		in = in[start+end+len(xPathArgumentEnd):]
 	}
-
-	return result
-}
-
-func (n *Node) xPathEscape(in string) string {
-	return xPathEscaper.Replace(in)
+	result.WriteString(in)
+	return result.String()
 }
 
 func (n *Node) xPathPrintf(xpath string, arguments ...interface{}) string {
@@ -147,84 +194,6 @@ func (n *Node) xPathPrintf(xpath string, arguments ...interface{}) string {
 	return fmt.Sprintf(xpath, list...)
 }
 
-func (n *Node) checkXPathCondition(condition string, node *Node, index int, count int) bool {
-	if condition == "" {
-		return true
-	}
-
-	// simple index (per W3C standard, counting begins at 1)
-	if xPathIndexCondition.MatchString(condition) {
-		expect, _ := strconv.ParseInt(condition, 10, 64)
-		xPathDebug("[xpath] self=%s, xPathIndexCondition: index=%d\n", node.Name, expect)
-		return expect == int64(index)
-	}
-
-	// attribute check
-	if matches := xPathAttributeCondition.FindStringSubmatch(condition); len(matches) > 0 {
-		if matches[1] == "*" {
-			xPathDebug("[xpath] self=%s, xPathAttributeCondition: name=%s, check=any\n", node.Name, matches[1])
-			return len(node.Attributes) > 0
-		}
-		if matches[3] == "" {
-			xPathDebug("[xpath] self=%s, xPathAttributeCondition: name=%s, check=exists\n", node.Name, matches[1])
-			_, exists := node.GetAttributeX(matches[1])
-			return exists
-		}
-		xPathDebug("[xpath] self=%s, xPathAttributeCondition: name=%s, check=value, value=%s\n", node.Name, matches[1], matches[3])
-		return n.xPathEscape(node.GetAttributeXValue(matches[1])) == matches[3]
-	}
-
-	// conditional position (last() and last()-N)
-	if matches := xPathLastCondition.FindStringSubmatch(condition); len(matches) > 0 {
-		shift := int64(0)
-		if matches[2] != "" {
-			shift, _ = strconv.ParseInt(matches[2], 10, 64)
-		}
-		xPathDebug("[xpath] self=%s, xPathLastCondition: shift=%d\n", node.Name, shift)
-		return index == count-int(shift)
-	}
-
-	panic(fmt.Errorf("XPath(): condition not yet supported: %s", condition))
-}
-
-func (n *Node) nextXPathToken(xpath string) (string, string) {
-	// we are done here
-	if len(xpath) == 0 {
-		return "", ""
-	}
-
-	// `//` -> deep search
-	if len(xpath) > 2 && xpath[0:2] == "//" {
-		return "//", xpath[2:]
-	}
-
-	// `/` -> jump to document root
-	if xpath[0:1] == "/" {
-		return "/", xpath[1:]
-	}
-
-	// find next splitter
-	index := strings.Index(xpath, "/")
-	if index == -1 {
-		// no more splitters -> last token
-		return xpath, ""
-	}
-
-	// splitter found -> split
-	return xpath[:index], xpath[index+1:]
-}
-
-func (n *Node) splitXPathToken(xpathToken string) (string, string) {
-	index := strings.Index(xpathToken, "[")
-
-	// no conditions set
-	if index == -1 {
-		return xpathToken, ""
-	}
-
-	return xpathToken[:index], xpathToken[index+1 : len(xpathToken)-1]
-}
-
 func xPathDebug(format string, arguments ...interface{}) {
 	if !xPathOutputDebug {
 		return
@@ -0,0 +1,281 @@
+package dom
+
+import (
+	"strings"
+	"testing"
+)
+
+const xPathTestLibrary = `<Library>` +
+	`<Shelf id="s1">` +
+	`<Book id="b1" lang="en"><Title>Go</Title><Price>30</Price><Status>OK</Status></Book>` +
+	`<Book id="b2" lang="de"><Title>XML</Title><Price>10</Price><Status>OK</Status></Book>` +
+	`</Shelf>` +
+	`<Shelf id="s2">` +
+	`<Book id="b3" lang="en"><Title>SOAP</Title><Price>25.5</Price><Status>SOLD</Status></Book>` +
+	`</Shelf>` +
+	`</Library>`
+
+func parseXPathTestDocument(t *testing.T, raw string) *Document {
+	t.Helper()
+	document, err := Parse([]byte(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return document
+}
+
+// describeXPathResult names the selected nodes by their id, their value or their name
+func describeXPathResult(list *NodeList) string {
+	result := []string{}
+	for _, node := range list.All() {
+		if id := node.GetAttributeValue("id"); id != "" {
+			result = append(result, id)
+		} else if value := node.String(); value != "" {
+			result = append(result, value)
+		} else {
+			result = append(result, node.Name)
+		}
+	}
+	return strings.Join(result, " ")
+}
+
+type xPathSelectTest struct {
+	xpath    string
+	expected string
+}
+
+func runXPathSelectTests(t *testing.T, context *Node, tests []xPathSelectTest) {
+	t.Helper()
+	for _, test := range tests {
+		t.Run(test.xpath, func(t *testing.T) {
+			if result := describeXPathResult(context.XPath(test.xpath)); result != test.expected {
+				t.Fatalf("expected [%s], got [%s]", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestXPathLocationPaths(t *testing.T) {
+	library := parseXPathTestDocument(t, xPathTestLibrary).Root
+	runXPathSelectTests(t, library, []xPathSelectTest{
+		// abbreviated syntax
+		{"Shelf/Book", "b1 b2 b3"},
+		{"Library/Shelf", "s1 s2"},
+		{"/Library", "Library"},
+		{"/Library/Shelf[2]/Book", "b3"},
+		{"*", "s1 s2"},
+		{"//Book", "b1 b2 b3"},
+		{"Shelf//Title", "Go XML SOAP"},
+		{"//Book[1]", "b1 b3"},
+		{"(//Book)[1]", "b1"},
+		{"Shelf/Book/.", "b1 b2 b3"},
+		{"//Title/..", "b1 b2 b3"},
+		{"//Book/../..", "Library"},
+		{"//@id", "s1 b1 b2 s2 b3"},
+		{"Shelf[1]/Book/@lang", "en de"},
+		{"//Title/text()", "Go XML SOAP"},
+		{"//Book[2]/node()", "XML 10 OK"},
+		{"//Missing", ""},
+
+		// axes
+		{"child::Shelf", "s1 s2"},
+		{"descendant::Price", "30 10 25.5"},
+		{"Shelf[1]/descendant-or-self::*[@id]", "s1 b1 b2"},
+		{"//Book/parent::Shelf", "s1 s2"},
+		{"//Title/ancestor::*", "Library s1 b1 b2 s2 b3"},
+		{"//Price/ancestor-or-self::Book", "b1 b2 b3"},
+		{"//Book[1]/following-sibling::*", "b2"},
+		{"//Book/preceding-sibling::Book", "b1"},
+		{"Shelf[1]/following::Book", "b3"},
+		{"Shelf[2]/preceding::Title", "Go XML"},
+		{"//Book/self::Book/attribute::lang", "en de en"},
+		{"//Book[@id='b2']/attribute::*", "b2 de"},
+		{"self::node()", "Library"},
+	})
+}
+
+func TestXPathCompiled(t *testing.T) {
+	library := parseXPathTestDocument(t, xPathTestLibrary).Root
+	books := MustCompileXPath("//Book")
+	if !books.SelectsNodes() || books.String() != "//Book" {
+		t.Fatalf("unexpected compiled expression [%s]", books.String())
+	}
+	for _, shelf := range library.XPath("Shelf").All() {
+		if books.Select(shelf).Len() != 3 {
+			t.Fatal("expected the absolute path to select all books")
+		}
+	}
+
+	for _, invalid := range []string{"Shelf[", "Shelf/", "//", "Shelf[1", "child::"} {
+		if _, err := CompileXPath(invalid); err == nil {
+			t.Fatalf("expected [%s] to be invalid", invalid)
+		}
+	}
+}
+
+func TestXPathPredicates(t *testing.T) {
+	library := parseXPathTestDocument(t, xPathTestLibrary).Root
+	runXPathSelectTests(t, library, []xPathSelectTest{
+		{"//Book[Status='OK' and Price > 10]", "b1"},
+		{"//Book[Price < 20 or @lang='en']", "b1 b2 b3"},
+		{"//Book[not(Status='OK')]", "b3"},
+		{"//Book[Price = 25.5]", "b3"},
+		{"//Book[last()]", "b2 b3"},
+		{"//Book[position() mod 2 = 1]", "b1 b3"},
+		{"//Book[Status='OK'][2]", "b2"},
+		{"//Book[contains(Title, 'O')]", "b3"},
+		{"//Book[starts-with(@id, 'b') and string-length(Title) = 3]", "b2"},
+		{"//Shelf[count(Book) = 2]", "s1"},
+		{"//Book[Title = /Library/Shelf[2]/Book/Title]", "b3"},
+		{"//Book[@id='b3'] | //Book[@id='b1']", "b1 b3"},
+		{"//Book[@lang != 'en']/Title", "XML"},
+	})
+}
+
+func TestXPathEval(t *testing.T) {
+	library := parseXPathTestDocument(t, xPathTestLibrary).Root
+	tests := []struct {
+		expr     string
+		expected interface{}
+	}{
+		{"sum(//Price)", 65.5},
+		{"count(//Book[Status='OK'])", 2.0},
+		{"10 div 4", 2.5},
+		{"-(3 - 5) * 2", 4.0},
+		{"7 mod 3", 1.0},
+		{"floor(2.5) + ceiling(2.5) + round(2.5)", 8.0},
+		{"number(//Book[@id='b3']/Price) > 25", true},
+		{"1 != 1 or 2 >= 2", true},
+		{"boolean(//Book[@lang='fr'])", false},
+		{"true() and not(false())", true},
+		{"concat(//Book[1]/Title, '-', //Book[@id='b3']/@lang)", "Go-en"},
+		{"normalize-space('  a   b ')", "a b"},
+		{"translate('abc', 'ab', 'AB')", "ABc"},
+		{"substring('12345', 2, 3)", "234"},
+		{"substring-before('a/b', '/')", "a"},
+		{"substring-after('a/b', '/')", "b"},
+		{"string(number('x'))", "NaN"},
+		{"string(//Book[2]/@lang)", "de"},
+		{"name(/Library/*[1])", "Shelf"},
+		{"local-name(//Book/@lang)", "lang"},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			result, err := library.Eval(test.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result != test.expected {
+				t.Fatalf("expected [%v] (%T), got [%v] (%T)", test.expected, test.expected, result, result)
+			}
+		})
+	}
+
+	for _, invalid := range []string{"1 +", "$variable", "unknown()", "count(1, 2)", "'unterminated"} {
+		if _, err := library.Eval(invalid); err == nil {
+			t.Fatalf("expected [%s] to fail", invalid)
+		}
+	}
+}
+
+func TestXPathArguments(t *testing.T) {
+	items := parseXPathTestDocument(t, `<Items>`+
+		`<Item id="slash"><Name>a/b</Name></Item>`+
+		`<Item id="escaped"><Name>a%2Fb</Name></Item>`+
+		`<Item id="quoted"><Name>it's "q" [x] %</Name></Item>`+
+		`</Items>`).Root
+
+	tests := []struct {
+		name      string
+		xpath     string
+		arguments []interface{}
+		expected  string
+	}{
+		{"argument with slash", "Item[Name='%s']", []interface{}{"a/b"}, "slash"},
+		{"argument with escape sequence", "Item[Name='%s']", []interface{}{"a%2Fb"}, "escaped"},
+		{"argument with quotes and brackets", "Item[Name=\"%s\"]", []interface{}{`it's "q" [x] %`}, "quoted"},
+		// literals of the expression itself are never unescaped
+		{"literal with escape sequence", "Item[Name='a%2Fb']", nil, "escaped"},
+		{"literal and argument", "Item[Name='a%%2Fb' or Name='%s']", []interface{}{"a/b"}, "slash escaped"},
+		{"name argument", "%s[@id='%s']", []interface{}{"Item", "quoted"}, "quoted"},
+		{"number argument", "Item[%s]", []interface{}{2}, "escaped"},
+		// arguments cannot inject steps or predicates
+		{"path argument", "Item/%s", []interface{}{"Name/.."}, ""},
+		{"predicate argument", "Item[@id='%s']", []interface{}{"x' or '1'='1"}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := describeXPathResult(items.XPath(test.xpath, test.arguments...)); result != test.expected {
+				t.Fatalf("expected [%s], got [%s]", test.expected, result)
+			}
+		})
+	}
+
+	count, err := items.Eval("count(Item[contains(Name, '%s')])", "/")
+	if err != nil || count != 1.0 {
+		t.Fatalf("expected 1, got %v (%v)", count, err)
+	}
+}
+
+const xPathTestEnvelope = `<s:Envelope xmlns:s="urn:soap" xmlns="urn:default"><s:Body>` +
+	`<t:Login xmlns:t="urn:t"><t:Token>abc</t:Token><Plain xmlns="">plain</Plain></t:Login>` +
+	`<Login>default</Login>` +
+	`</s:Body></s:Envelope>`
+
+func TestXPathNamespaces(t *testing.T) {
+	envelope := parseXPathTestDocument(t, xPathTestEnvelope).Root
+	namespaces := map[string]string{"soap": "urn:soap", "x": "urn:t", "d": "urn:default"}
+
+	tests := []struct {
+		xpath    string
+		expected string
+	}{
+		{"/soap:Envelope/soap:Body/x:Login/x:Token", "abc"},
+		{"//x:*", "Login abc"},
+		{"//d:Login", "default"},
+		{"//soap:Body/*/x:Token", "abc"},
+		// unprefixed names select nodes without namespace only
+		{"//Plain", "plain"},
+		{"//Login", ""},
+		{"//x:Login[x:Token='abc']/Plain", "plain"},
+		{"//*[namespace-uri()='urn:t']", "Login abc"},
+		{"//*[local-name()='Login']", "Login default"},
+	}
+	for _, test := range tests {
+		t.Run(test.xpath, func(t *testing.T) {
+			if result := describeXPathResult(envelope.XPathNS(test.xpath, namespaces)); result != test.expected {
+				t.Fatalf("expected [%s], got [%s]", test.expected, result)
+			}
+		})
+	}
+
+	// without bindings names match by their local or prefixed name
+	runXPathSelectTests(t, envelope, []xPathSelectTest{
+		{"//Token", "abc"},
+		{"//t:Token", "abc"},
+		{"//Login", "Login default"},
+	})
+
+	count, err := envelope.EvalNS("count(//x:*)", namespaces)
+	if err != nil || count != 2.0 {
+		t.Fatalf("expected 2, got %v (%v)", count, err)
+	}
+	if _, err := envelope.EvalNS("//y:Login", namespaces); err == nil {
+		t.Fatal("expected an unbound prefix to fail")
+	}
+	if _, err := CompileXPathNS("//y:Login", namespaces); err == nil {
+		t.Fatal("expected an unbound prefix to fail")
+	}
+
+	token := MustCompileXPath("//x:Token")
+	if token.Select(envelope).Len() != 0 {
+		t.Fatal("expected prefixes of the document not to be matched by a different prefix")
+	}
+	tokenNS, err := CompileXPathNS("//x:Token", namespaces)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if describeXPathResult(tokenNS.Select(envelope)) != "abc" {
+		t.Fatal("expected the bound prefix to match")
+	}
+}
@@ -1,8 +1,10 @@
 package dom
 
 import (
+	"bytes"
 	"encoding/xml"
 	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -24,8 +26,6 @@ func (n *Node) XML() string {
 	return n.xml(0)
 }
 
-// @todo lots of escaping
-// @todo CDATA
 func (n *Node) xml(indent int) string {
 	result := ""
 	indentation := strings.Repeat(" ", indent)
@@ -45,16 +45,23 @@ func (n *Node) xml(indent int) string {
 		if attr.Namespace != nil && attr.Namespace.Abbreviation != "" {
 			result += attr.Namespace.Abbreviation + ":"
 		}
-		result += attr.Name + "=\"" + attr.Value + "\""
+		result += attr.Name + "=\"" + xmlAttributeEscaper.Replace(attr.Value) + "\""
 	}
 
-	// namespaces
+	// namespaces, sorted by abbreviation for a stable output
+	namespaces := make([]*Namespace, 0, len(n.NamespaceMapping))
 	for _, ns := range n.NamespaceMapping {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].Abbreviation < namespaces[j].Abbreviation
+	})
+	for _, ns := range namespaces {
 		result += " xmlns"
 		if ns.Abbreviation != "" {
 			result += ":" + ns.Abbreviation
 		}
-		result += "=\"" + ns.Name + "\""
+		result += "=\"" + xmlAttributeEscaper.Replace(ns.Name) + "\""
 	}
 
 	// short closing tag
@@ -63,7 +70,7 @@ func (n *Node) xml(indent int) string {
 		return result
 	}
 
-	result += ">" + n.String()
+	result += ">" + n.Value.xml()
 
 	// children
 	if n.Children.Len() > 0 {
@@ -109,26 +116,48 @@ func (n *Node) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	// save namespace
 	n.Namespace = n.ResolveNS(start.Name.Space)
 
-	// traverse
+	// traverse; all text of the element is joined, CDATA sections are kept as such if requested
 	end := start.End()
+	text, allCDATA := []byte(nil), true
 	for {
+		offset := d.InputOffset()
 		token, err := d.Token()
 		if err != nil {
 			return err
 		}
 
 		if token == end {
+			preserveCDATA := allCDATA && n.Document != nil && n.Document.preserveCDATA
+			// whitespace around child elements is indentation (XML() writes it as well), keeping it would
+			// pile it up with every round trip
+			if n.Children.Len() > 0 && !preserveCDATA {
+				if text = bytes.TrimSpace(text); len(text) == 0 {
+					text = nil
+				}
+			}
+			if text != nil {
+				if preserveCDATA {
+					n.Value.value = CDATA(text)
+				} else {
+					n.Value.value = string(text)
+				}
+			}
 			return nil
 		}
 
 		switch t := token.(type) {
 		case xml.StartElement:
 			child := &Node{Document: n.Document, Parent: n}
-			d.DecodeElement(child, &t)
+			if err := d.DecodeElement(child, &t); err != nil {
+				return err
+			}
 			n.Children.Append(child)
 
 		case xml.CharData:
-			n.Value.value = string(t)
+			text = append(text, t...)
+			if n.Document == nil || !n.Document.isCDATA(offset, d.InputOffset()) {
+				allCDATA = false
+			}
 
 		case xml.EndElement:
 			return fmt.Errorf("Unexpected xml.EndElement")
@@ -139,11 +168,9 @@ func (n *Node) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 // SetAttribute sets a given attribute to the current Node
 func (n *Node) SetAttribute(namespace string, name string, value string) {
 	// replace
-	for _, attr := range n.Attributes {
-		if attr.Name == name {
-			attr.Value = value
-			return
-		}
+	if attr, exists := n.GetAttributeNS(namespace, name); exists {
+		attr.Value = value
+		return
 	}
 
 	// append (default ns)
@@ -158,12 +185,38 @@ func (n *Node) SetAttribute(namespace string, name string, value string) {
 
 	// append (resolve ns)
 	n.Attributes = append(n.Attributes, &Attribute{
-		Namespace: n.ResolveNS(namespace),
+		Namespace: n.resolveAttributeNS(namespace),
 		Name:      name,
 		Value:     value,
 	})
 }
 
+// resolveAttributeNS resolves the namespace to a prefixed one, the default namespace does not apply to attributes.
+// A new prefix is declared on the nearest node not binding the namespace yet, so no default binding is replaced
+func (n *Node) resolveAttributeNS(namespace string) *Namespace {
+	if namespace == xmlNamespace {
+		return xmlNamespaceBinding
+	}
+	var declaring *Node
+	for node := n; node != nil; node = node.Parent {
+		ns, exists := node.NamespaceMapping[namespace]
+		if exists && ns.Abbreviation != "" {
+			return ns
+		}
+		if !exists && declaring == nil {
+			declaring = node
+		}
+	}
+	if declaring != nil {
+		return declaring.RegisterNS(namespace, n.NewNSAbbrev())
+	}
+
+	// all nodes up to the root bind it as default: prefix the binding of this node, it stays in the namespace
+	ns := n.NamespaceMapping[namespace]
+	ns.Abbreviation = n.NewNSAbbrev()
+	return ns
+}
+
 // GetAttribute retrieves an attribute
 func (n *Node) GetAttribute(name string) (*Attribute, bool) {
 	for _, attr := range n.Attributes {
@@ -174,6 +227,16 @@ func (n *Node) GetAttribute(name string) (*Attribute, bool) {
 	return nil, false
 }
 
+// GetAttributeNS retrieves an attribute by its namespace (empty for unqualified attributes) and local name
+func (n *Node) GetAttributeNS(namespace string, name string) (*Attribute, bool) {
+	for _, attr := range n.Attributes {
+		if attr.Name == name && attr.InNamespace(namespace) {
+			return attr, true
+		}
+	}
+	return nil, false
+}
+
 // GetAttributeValue returns the value of a given attribute (empty string if it doesn't exist)
 func (n *Node) GetAttributeValue(name string) string {
 	if attr, exists := n.GetAttribute(name); exists {
@@ -210,6 +273,11 @@ func (n *Node) resolveNS(namespace string, rel *Node) *Namespace {
 		return nil
 	}
 
+	// the xml prefix is bound by definition
+	if namespace == xmlNamespace {
+		return xmlNamespaceBinding
+	}
+
 	// see if we know this namespace
 	if n.NamespaceMapping != nil {
 		if ns, exists := n.NamespaceMapping[namespace]; exists {
@@ -233,22 +301,35 @@ func (n *Node) resolveNS(namespace string, rel *Node) *Namespace {
 
 // ResolveNSAbbrev tries to resolve the given Namespace by its Abbreviation
 func (n *Node) ResolveNSAbbrev(abbreviation string) *Namespace {
+	namespace, err := n.LookupNSAbbrev(abbreviation)
+	if err != nil {
+		panic(err)
+	}
+	return namespace
+}
+
+// LookupNSAbbrev resolves the given Namespace by its Abbreviation, returning ErrUnknownNamespace if unknown
+func (n *Node) LookupNSAbbrev(abbreviation string) (*Namespace, error) {
+	if abbreviation == "xml" {
+		return xmlNamespaceBinding, nil
+	}
+
 	// see if we know this namespace
 	if n.NamespaceMapping != nil {
 		for _, chk := range n.NamespaceMapping {
 			if chk.Abbreviation == abbreviation {
-				return chk
+				return chk, nil
 			}
 		}
 	}
 
 	// ask parent
 	if n.Parent != nil {
-		return n.Parent.ResolveNSAbbrev(abbreviation)
+		return n.Parent.LookupNSAbbrev(abbreviation)
 	}
 
 	// bad luck
-	panic(fmt.Errorf("ResolveNSAbbrev(): unknown namespace abbreviation: [%s]", abbreviation))
+	return nil, fmt.Errorf("ResolveNSAbbrev(): %w: [%s]", ErrUnknownNamespace, abbreviation)
 }
 
 // SetDefaultNS sets the default namespace for this Node and all children
@@ -299,6 +380,11 @@ func (n *Node) SetValue(val interface{}) {
 	n.Value.SetValue(val)
 }
 
+// SetCDATA sets the value of this Node, written as CDATA section
+func (n *Node) SetCDATA(text string) {
+	n.Value.SetValue(CDATA(text))
+}
+
 // NewChildren appends a new child Node to this Node and returns it
 func (n *Node) NewChildren(name string, namespace string) *Node {
 	newNode := &Node{
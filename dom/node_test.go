@@ -0,0 +1,143 @@
+package dom
+
+import (
+	"testing"
+)
+
+const nodeTestIndented = `<Order xmlns="urn:order">
+  <Customer id="c&amp;1">
+    <Name> Jane  Doe </Name>
+    <Note>a &lt; b &amp; "c"</Note>
+    <Blank>   </Blank>
+  </Customer>
+  <Comment>see <Ref>1</Ref></Comment>
+</Order>`
+
+func TestNodeXMLRoundTrip(t *testing.T) {
+	document, err := Parse([]byte(nodeTestIndented))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := document.Root.XML()
+
+	// indentation between children is dropped, so the output is stable
+	current := first
+	for i := 0; i < 3; i++ {
+		reparsed, err := Parse([]byte(current))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if current = reparsed.Root.XML(); current != first {
+			t.Fatalf("round trip %d changed the output:\n%s\n--- expected ---\n%s", i+1, current, first)
+		}
+	}
+
+	tests := []struct {
+		xpath    string
+		expected string
+	}{
+		{"Customer", ""},
+		{"Customer/@id", "c&1"},
+		{"Customer/Name", " Jane  Doe "},
+		{"Customer/Note", `a < b & "c"`},
+		{"Customer/Blank", "   "},
+		{"Comment", "see"},
+		{"Comment/Ref", "1"},
+	}
+	for _, test := range tests {
+		if value := document.Root.XPath(test.xpath).First().String(); value != test.expected {
+			t.Fatalf("%s: expected [%s], got [%s]", test.xpath, test.expected, value)
+		}
+	}
+	if document.Root.XPath("Customer").First().Value.value != nil {
+		t.Fatal("expected no value for indentation")
+	}
+}
+
+func TestNodeCDATA(t *testing.T) {
+	raw := []byte(`<a><![CDATA[  ]]><b><![CDATA[x < y]]></b><c>text</c></a>`)
+
+	preserved, err := Parse(raw, PreserveCDATA())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "<a><![CDATA[  ]]>\n  <b><![CDATA[x < y]]></b>\n  <c>text</c>\n</a>\n"; preserved.Root.XML() != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, preserved.Root.XML())
+	}
+
+	plain, err := Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "<a>\n  <b>x &lt; y</b>\n  <c>text</c>\n</a>\n"; plain.Root.XML() != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, plain.Root.XML())
+	}
+
+	node := NewDocument("v").Root
+	node.SetCDATA("a]]>b")
+	if expected := "<v><![CDATA[a]]]]><![CDATA[>b]]></v>\n"; node.XML() != expected {
+		t.Fatalf("expected %s, got %s", expected, node.XML())
+	}
+}
+
+func TestNodeAttributesNS(t *testing.T) {
+	document, err := Parse([]byte(`<a xmlns:p="urn:p" xmlns:q="urn:q" p:code="1" q:code="2" code="3"/>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := document.Root
+	if len(root.Attributes) != 3 {
+		t.Fatalf("expected attributes of the same local name to be kept, got %d", len(root.Attributes))
+	}
+
+	root.SetAttribute("urn:q", "code", "4")
+	root.SetAttribute("urn:r", "code", "5")
+	for namespace, expected := range map[string]string{"urn:p": "1", "urn:q": "4", "": "3", "urn:r": "5"} {
+		if attr, exists := root.GetAttributeNS(namespace, "code"); !exists || attr.Value != expected {
+			t.Fatalf("expected {%s}code to be [%s]", namespace, expected)
+		}
+	}
+	if _, exists := root.GetAttributeNS("urn:s", "code"); exists {
+		t.Fatal("expected no attribute in another namespace")
+	}
+}
+
+func TestNodeAttributeInDefaultNamespace(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		element string
+	}{
+		{"inherited default", `<r xmlns="urn:a"><a/></r>`, "a"},
+		{"own default", `<r><a xmlns="urn:a"/></r>`, "a"},
+		{"own and inherited default", `<r xmlns="urn:a"><a xmlns="urn:a"><c/></a></r>`, "a"},
+		{"root default", `<a xmlns="urn:a"><c/></a>`, "a"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			document, err := Parse([]byte(test.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			document.Root.XPath("//%s", test.element).First().SetAttribute("urn:a", "code", "1")
+
+			// the element keeps its namespace, the attribute gets a prefix
+			reparsed, err := Parse([]byte(document.Root.XML()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			element := reparsed.Root.XPathNS("//a:%s", map[string]string{"a": "urn:a"}, test.element).First()
+			if !element.Exists {
+				t.Fatalf("expected [%s] to stay in its namespace:\n%s", test.element, document.Root.XML())
+			}
+			if attr, exists := element.GetAttributeNS("urn:a", "code"); !exists || attr.Value != "1" {
+				t.Fatalf("expected the qualified attribute:\n%s", document.Root.XML())
+			}
+			for _, child := range reparsed.Root.XPath("//c").All() {
+				if child.Namespace == nil || child.Namespace.Name != "urn:a" {
+					t.Fatalf("expected children to stay in the namespace:\n%s", document.Root.XML())
+				}
+			}
+		})
+	}
+}
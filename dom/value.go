@@ -2,6 +2,28 @@ package dom
 
 import (
 	"fmt"
+	"strings"
+)
+
+// CDATA is a text value written as CDATA section
+type CDATA string
+
+var (
+	xmlTextEscaper = strings.NewReplacer(
+		"&", "&amp;",
+		"<", "&lt;",
+		">", "&gt;",
+		"\r", "&#xD;",
+	)
+	xmlAttributeEscaper = strings.NewReplacer(
+		"&", "&amp;",
+		"<", "&lt;",
+		">", "&gt;",
+		`"`, "&quot;",
+		"\t", "&#x9;",
+		"\n", "&#xA;",
+		"\r", "&#xD;",
+	)
 )
 
 // Value wraps the value of a Node so we have types here
@@ -17,6 +39,21 @@ func (v *Value) String() string {
 	return fmt.Sprint(v.value)
 }
 
+// IsCDATA returns true if the stored value is written as CDATA section
+func (v *Value) IsCDATA() bool {
+	_, ok := v.value.(CDATA)
+	return ok
+}
+
+// xml returns the escaped value, or the CDATA section
+func (v *Value) xml() string {
+	if cdata, ok := v.value.(CDATA); ok {
+		// "]]>" cannot be part of a section, so it is split across two
+		return "<![CDATA[" + strings.ReplaceAll(string(cdata), "]]>", "]]]]><![CDATA[>") + "]]>"
+	}
+	return xmlTextEscaper.Replace(v.String())
+}
+
 // IsNil returns true if the stored value is nil
 func (v *Value) IsNil() bool {
 	return v.value == nil
@@ -0,0 +1,417 @@
+package dom
+
+import (
+	"sort"
+	"strings"
+)
+
+// xPathItemKind defines the kind of node an xPathItem refers to
+type xPathItemKind int
+
+const (
+	xPathItemElement xPathItemKind = iota
+	xPathItemAttribute
+	xPathItemText
+	xPathItemDocument
+)
+
+// xPathItem is a node of the XPath data model: an element, one of its attributes, its text or the document.
+// For attributes and text node is the owning element, for the document it is the topmost element
+type xPathItem struct {
+	kind      xPathItemKind
+	node      *Node
+	attribute *Attribute
+}
+
+// document returns the document node of the item
+func (i xPathItem) document() xPathItem {
+	top := i.node
+	for top.Parent != nil {
+		top = top.Parent
+	}
+	return xPathItem{kind: xPathItemDocument, node: top}
+}
+
+// parent returns the parent of the item, false for the document node
+func (i xPathItem) parent() (xPathItem, bool) {
+	switch i.kind {
+	case xPathItemDocument:
+		return xPathItem{}, false
+	case xPathItemAttribute, xPathItemText:
+		return xPathItem{kind: xPathItemElement, node: i.node}, true
+	}
+	if i.node.Parent == nil {
+		return i.document(), true
+	}
+	return xPathItem{kind: xPathItemElement, node: i.node.Parent}, true
+}
+
+// children returns the child nodes of the item in document order; our DOM keeps the text before the elements
+func (i xPathItem) children() []xPathItem {
+	switch i.kind {
+	case xPathItemDocument:
+		return []xPathItem{{kind: xPathItemElement, node: i.node}}
+	case xPathItemElement:
+		result := []xPathItem{}
+		if !i.node.Value.IsNil() {
+			result = append(result, xPathItem{kind: xPathItemText, node: i.node})
+		}
+		for _, child := range i.node.Children.All() {
+			result = append(result, xPathItem{kind: xPathItemElement, node: child})
+		}
+		return result
+	}
+	return nil
+}
+
+// descendants appends all descendants of the item in document order
+func (i xPathItem) descendants(result []xPathItem) []xPathItem {
+	for _, child := range i.children() {
+		result = append(result, child)
+		result = child.descendants(result)
+	}
+	return result
+}
+
+// isAncestorOf checks if the item is an ancestor of other
+func (i xPathItem) isAncestorOf(other xPathItem) bool {
+	for parent, exists := other.parent(); exists; parent, exists = parent.parent() {
+		if parent == i {
+			return true
+		}
+	}
+	return false
+}
+
+// siblings returns the siblings of the item before and after it, in document order
+func (i xPathItem) siblings() ([]xPathItem, []xPathItem) {
+	if i.kind == xPathItemAttribute || i.kind == xPathItemDocument {
+		return nil, nil
+	}
+	parent, _ := i.parent()
+	if parent.kind == xPathItemDocument {
+		return nil, nil
+	}
+	all := parent.children()
+	for index, sibling := range all {
+		if sibling == i {
+			return all[:index], all[index+1:]
+		}
+	}
+	return nil, nil
+}
+
+// axis returns the nodes on the given axis of the item, in axis order
+func (i xPathItem) axis(axis xPathAxis) []xPathItem {
+	switch axis {
+	case xPathAxisSelf:
+		return []xPathItem{i}
+
+	case xPathAxisChild:
+		return i.children()
+
+	case xPathAxisDescendant:
+		return i.descendants(nil)
+
+	case xPathAxisDescendantOrSelf:
+		return i.descendants([]xPathItem{i})
+
+	case xPathAxisParent:
+		if parent, exists := i.parent(); exists {
+			return []xPathItem{parent}
+		}
+		return nil
+
+	case xPathAxisAncestor, xPathAxisAncestorOrSelf:
+		result := []xPathItem{}
+		if axis == xPathAxisAncestorOrSelf {
+			result = append(result, i)
+		}
+		for parent, exists := i.parent(); exists; parent, exists = parent.parent() {
+			result = append(result, parent)
+		}
+		return result
+
+	case xPathAxisFollowingSibling:
+		_, after := i.siblings()
+		return after
+
+	case xPathAxisPrecedingSibling:
+		before, _ := i.siblings()
+		return reverseXPathItems(append([]xPathItem{}, before...))
+
+	case xPathAxisFollowing, xPathAxisPreceding:
+		if i.kind == xPathItemDocument {
+			return nil
+		}
+		// attributes are positioned by their element
+		anchor := i
+		if i.kind == xPathItemAttribute {
+			anchor = xPathItem{kind: xPathItemElement, node: i.node}
+		}
+		all := i.document().descendants(nil)
+		index := 0
+		for index < len(all) && all[index] != anchor {
+			index++
+		}
+
+		result := []xPathItem{}
+		if axis == xPathAxisFollowing {
+			for _, item := range all[minInt(index+1, len(all)):] {
+				if !i.isAncestorOf(item) {
+					result = append(result, item)
+				}
+			}
+			return result
+		}
+		for _, item := range all[:index] {
+			if !item.isAncestorOf(anchor) {
+				result = append(result, item)
+			}
+		}
+		return reverseXPathItems(result)
+
+	case xPathAxisAttribute:
+		if i.kind != xPathItemElement {
+			return nil
+		}
+		result := []xPathItem{}
+		for _, attr := range i.node.Attributes {
+			result = append(result, xPathItem{kind: xPathItemAttribute, node: i.node, attribute: attr})
+		}
+		return result
+	}
+	return nil
+}
+
+// matches checks the node test of the step against the item
+func (s *xPathStep) matches(item xPathItem) bool {
+	switch s.nodeType {
+	case xPathNodeTypeNode:
+		return true
+	case xPathNodeTypeText:
+		return item.kind == xPathItemText
+	case xPathNodeTypeComment, xPathNodeTypeProcessingInstruction:
+		return false
+	}
+
+	// name tests only select the principal node type of the axis
+	if s.axis == xPathAxisAttribute {
+		if item.kind != xPathItemAttribute {
+			return false
+		}
+	} else if item.kind != xPathItemElement {
+		return false
+	}
+
+	if s.namespaceAware {
+		local, namespace := item.expandedName()
+		if s.name != "*" && s.name != local {
+			return false
+		}
+		return (s.name == "*" && !s.prefixed) || namespace == s.namespace
+	}
+	if s.name == "*" {
+		return true
+	}
+	if item.kind == xPathItemAttribute {
+		return matchesXPathName(s.name, item.attribute.Name, item.attribute.Namespace)
+	}
+	return matchesXPathName(s.name, item.node.Name, item.node.Namespace)
+}
+
+// expandedName returns the local name and the namespace URI of an element or attribute.
+// Elements without Namespace are in the namespace of their prefix (if part of the name) or the default namespace
+func (i xPathItem) expandedName() (string, string) {
+	name, namespace, owner := i.node.Name, i.node.Namespace, i.node
+	if i.kind == xPathItemAttribute {
+		name, namespace = i.attribute.Name, i.attribute.Namespace
+	}
+
+	prefix, local := SplitFQName(name)
+	if namespace != nil {
+		return local, namespace.Name
+	}
+	// unprefixed attributes are in no namespace
+	if prefix == "" && i.kind == xPathItemAttribute {
+		return local, ""
+	}
+	if resolved, err := owner.LookupNSAbbrev(prefix); err == nil {
+		return local, resolved.Name
+	}
+	return local, ""
+}
+
+// matchesXPathName compares a name test to the plain or prefixed name of a node
+func matchesXPathName(test string, name string, namespace *Namespace) bool {
+	if test == name {
+		return true
+	}
+	if namespace != nil && namespace.Abbreviation != "" {
+		return test == namespace.Abbreviation+":"+name || test == namespace.Abbreviation+":*"
+	}
+	return false
+}
+
+// evaluate selects the nodes of the step for all context items, in document order
+func (s *xPathStep) evaluate(contexts xPathItems) xPathItems {
+	result := xPathItems{}
+	seen := map[xPathItem]bool{}
+	for _, context := range contexts {
+		candidates := xPathItems{}
+		for _, item := range context.axis(s.axis) {
+			if s.matches(item) {
+				candidates = append(candidates, item)
+			}
+		}
+
+		// predicates filter by proximity position
+		for _, predicate := range s.predicates {
+			candidates = filterXPathItems(candidates, predicate)
+		}
+
+		for _, item := range candidates {
+			if !seen[item] {
+				seen[item] = true
+				result = append(result, item)
+			}
+		}
+	}
+
+	if len(contexts) > 1 || s.axis.reverse() {
+		sortXPathItems(result)
+	}
+	return result
+}
+
+// xPathItems is a node-set, in document order
+type xPathItems []xPathItem
+
+// evaluate selects the nodes of the location path starting at the context node
+func (l *xPathLocation) evaluate(context xPathContext) interface{} {
+	if l.absolute {
+		return l.evaluateFrom(xPathItems{context.item.document()})
+	}
+	return l.evaluateFrom(xPathItems{context.item})
+}
+
+func (l *xPathLocation) valueType() xPathType {
+	return xPathNodeSet
+}
+
+// evaluateFrom applies all steps of the location path to the given items
+func (l *xPathLocation) evaluateFrom(items xPathItems) xPathItems {
+	for _, step := range l.steps {
+		items = step.evaluate(items)
+		if len(items) == 0 {
+			break
+		}
+	}
+	return items
+}
+
+// stringValue returns the string-value of the item: the text of all its descendants for elements
+func (i xPathItem) stringValue() string {
+	switch i.kind {
+	case xPathItemAttribute:
+		return i.attribute.Value
+	case xPathItemText:
+		return i.node.Value.String()
+	}
+	result := strings.Builder{}
+	for _, descendant := range i.descendants(nil) {
+		if descendant.kind == xPathItemText {
+			result.WriteString(descendant.node.Value.String())
+		}
+	}
+	return result.String()
+}
+
+// nodeList converts the items to Nodes: attributes and text as detached Nodes, the document as its root element
+func (items xPathItems) nodeList() *NodeList {
+	result := &NodeList{}
+	for _, item := range items {
+		switch item.kind {
+		case xPathItemElement, xPathItemDocument:
+			result.Append(item.node)
+		case xPathItemAttribute:
+			node := &Node{
+				Namespace: item.attribute.Namespace,
+				Document:  item.node.Document,
+				Parent:    item.node,
+				Name:      item.attribute.Name,
+				Exists:    true,
+			}
+			node.Value.SetValue(item.attribute.Value)
+			result.Append(node)
+		case xPathItemText:
+			node := &Node{
+				Document: item.node.Document,
+				Parent:   item.node,
+				Name:     "#text",
+				Exists:   true,
+			}
+			item.node.Value.CopyValue(node)
+			result.Append(node)
+		}
+	}
+	return result
+}
+
+// order returns the position of the item in document order as a comparable path
+func (i xPathItem) order() []int {
+	switch i.kind {
+	case xPathItemDocument:
+		return nil
+	case xPathItemAttribute:
+		for index, attr := range i.node.Attributes {
+			if attr == i.attribute {
+				return append(xPathItem{kind: xPathItemElement, node: i.node}.order(), 0, index)
+			}
+		}
+	case xPathItemText:
+		return append(xPathItem{kind: xPathItemElement, node: i.node}.order(), 1)
+	}
+
+	if i.node.Parent == nil {
+		return []int{2}
+	}
+	parent := xPathItem{kind: xPathItemElement, node: i.node.Parent}
+	for index, sibling := range i.node.Parent.Children.All() {
+		if sibling == i.node {
+			return append(parent.order(), index+2)
+		}
+	}
+	return parent.order()
+}
+
+// sortXPathItems sorts the items in document order
+func sortXPathItems(items []xPathItem) {
+	orders := make(map[xPathItem][]int, len(items))
+	for _, item := range items {
+		orders[item] = item.order()
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := orders[items[i]], orders[items[j]]
+		for index := 0; index < len(a) && index < len(b); index++ {
+			if a[index] != b[index] {
+				return a[index] < b[index]
+			}
+		}
+		return len(a) < len(b)
+	})
+}
+
+func reverseXPathItems(items []xPathItem) []xPathItem {
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+	return items
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,308 @@
+package dom
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// xPathType defines the static result type of an expression
+type xPathType int
+
+const (
+	xPathNodeSet xPathType = iota
+	xPathString
+	xPathNumberType
+	xPathBoolean
+)
+
+var xPathNumberFormat = regexp.MustCompile(`^-?([0-9]+(\.[0-9]*)?|\.[0-9]+)$`)
+
+// xPathContext is the evaluation context: the context node with its position in the context node-set
+type xPathContext struct {
+	item     xPathItem
+	position int
+	size     int
+}
+
+// xPathExpr is a parsed XPath expression. It evaluates to xPathItems, string, float64 or bool
+type xPathExpr interface {
+	evaluate(context xPathContext) interface{}
+	valueType() xPathType
+}
+
+// xPathLiteral is a string literal
+type xPathLiteral string
+
+func (e xPathLiteral) evaluate(context xPathContext) interface{} {
+	return string(e)
+}
+
+func (e xPathLiteral) valueType() xPathType {
+	return xPathString
+}
+
+// xPathNumber is a number literal
+type xPathNumber float64
+
+func (e xPathNumber) evaluate(context xPathContext) interface{} {
+	return float64(e)
+}
+
+func (e xPathNumber) valueType() xPathType {
+	return xPathNumberType
+}
+
+// xPathBinary is an operation on two operands
+type xPathBinary struct {
+	operator string
+	left     xPathExpr
+	right    xPathExpr
+}
+
+func (e *xPathBinary) evaluate(context xPathContext) interface{} {
+	switch e.operator {
+	case "or":
+		return xPathToBoolean(e.left.evaluate(context)) || xPathToBoolean(e.right.evaluate(context))
+	case "and":
+		return xPathToBoolean(e.left.evaluate(context)) && xPathToBoolean(e.right.evaluate(context))
+	case "=", "!=", "<", "<=", ">", ">=":
+		return xPathCompare(e.operator, e.left.evaluate(context), e.right.evaluate(context))
+	}
+
+	left, right := xPathToNumber(e.left.evaluate(context)), xPathToNumber(e.right.evaluate(context))
+	switch e.operator {
+	case "+":
+		return left + right
+	case "-":
+		return left - right
+	case "*":
+		return left * right
+	case "div":
+		return left / right
+	}
+	return math.Mod(left, right)
+}
+
+func (e *xPathBinary) valueType() xPathType {
+	switch e.operator {
+	case "+", "-", "*", "div", "mod":
+		return xPathNumberType
+	}
+	return xPathBoolean
+}
+
+// xPathNegation is the unary minus
+type xPathNegation struct {
+	operand xPathExpr
+}
+
+func (e *xPathNegation) evaluate(context xPathContext) interface{} {
+	return -xPathToNumber(e.operand.evaluate(context))
+}
+
+func (e *xPathNegation) valueType() xPathType {
+	return xPathNumberType
+}
+
+// xPathUnion merges two node-sets
+type xPathUnion struct {
+	left  xPathExpr
+	right xPathExpr
+}
+
+func (e *xPathUnion) evaluate(context xPathContext) interface{} {
+	left, right := e.left.evaluate(context).(xPathItems), e.right.evaluate(context).(xPathItems)
+	result := append(xPathItems{}, left...)
+	seen := map[xPathItem]bool{}
+	for _, item := range left {
+		seen[item] = true
+	}
+	for _, item := range right {
+		if !seen[item] {
+			result = append(result, item)
+		}
+	}
+	sortXPathItems(result)
+	return result
+}
+
+func (e *xPathUnion) valueType() xPathType {
+	return xPathNodeSet
+}
+
+// xPathFilter filters the node-set of a primary expression and optionally continues with a relative path
+type xPathFilter struct {
+	primary    xPathExpr
+	predicates []xPathExpr
+	location   *xPathLocation
+}
+
+func (e *xPathFilter) evaluate(context xPathContext) interface{} {
+	items := e.primary.evaluate(context).(xPathItems)
+	for _, predicate := range e.predicates {
+		items = filterXPathItems(items, predicate)
+	}
+	if e.location != nil {
+		return e.location.evaluateFrom(items)
+	}
+	return items
+}
+
+func (e *xPathFilter) valueType() xPathType {
+	return xPathNodeSet
+}
+
+// filterXPathItems keeps the items matching the predicate, positions are taken from the order of items
+func filterXPathItems(items xPathItems, predicate xPathExpr) xPathItems {
+	result := xPathItems{}
+	for index, item := range items {
+		value := predicate.evaluate(xPathContext{item: item, position: index + 1, size: len(items)})
+
+		// numbers select by position
+		if number, ok := value.(float64); ok {
+			if number == float64(index+1) {
+				result = append(result, item)
+			}
+		} else if xPathToBoolean(value) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// xPathCompare compares two values following the XPath rules for node-sets
+func xPathCompare(operator string, left interface{}, right interface{}) bool {
+	leftItems, leftIsSet := left.(xPathItems)
+	rightItems, rightIsSet := right.(xPathItems)
+
+	switch {
+	case leftIsSet && rightIsSet:
+		for _, l := range leftItems {
+			for _, r := range rightItems {
+				if xPathCompareAtomic(operator, l.stringValue(), r.stringValue()) {
+					return true
+				}
+			}
+		}
+		return false
+
+	case leftIsSet:
+		if _, ok := right.(bool); ok {
+			return xPathCompareAtomic(operator, xPathToBoolean(left), right)
+		}
+		for _, l := range leftItems {
+			if xPathCompareAtomic(operator, l.stringValue(), right) {
+				return true
+			}
+		}
+		return false
+
+	case rightIsSet:
+		if _, ok := left.(bool); ok {
+			return xPathCompareAtomic(operator, left, xPathToBoolean(right))
+		}
+		for _, r := range rightItems {
+			if xPathCompareAtomic(operator, left, r.stringValue()) {
+				return true
+			}
+		}
+		return false
+	}
+	return xPathCompareAtomic(operator, left, right)
+}
+
+// xPathCompareAtomic compares two strings, numbers or booleans
+func xPathCompareAtomic(operator string, left interface{}, right interface{}) bool {
+	if operator == "=" || operator == "!=" {
+		var equal bool
+		_, leftIsBool := left.(bool)
+		_, rightIsBool := right.(bool)
+		_, leftIsNumber := left.(float64)
+		_, rightIsNumber := right.(float64)
+
+		switch {
+		case leftIsBool || rightIsBool:
+			equal = xPathToBoolean(left) == xPathToBoolean(right)
+		case leftIsNumber || rightIsNumber:
+			equal = xPathToNumber(left) == xPathToNumber(right)
+		default:
+			equal = xPathToString(left) == xPathToString(right)
+		}
+		return equal == (operator == "=")
+	}
+
+	l, r := xPathToNumber(left), xPathToNumber(right)
+	switch operator {
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	}
+	return l >= r
+}
+
+// xPathToString converts a value to string as the string() function does
+func xPathToString(value interface{}) string {
+	switch v := value.(type) {
+	case xPathItems:
+		if len(v) == 0 {
+			return ""
+		}
+		return v[0].stringValue()
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return "NaN"
+		case math.IsInf(v, 1):
+			return "Infinity"
+		case math.IsInf(v, -1):
+			return "-Infinity"
+		case v == 0:
+			return "0"
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	}
+	return value.(string)
+}
+
+// xPathToNumber converts a value to a number as the number() function does
+func xPathToNumber(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	}
+
+	text := strings.TrimSpace(xPathToString(value))
+	if !xPathNumberFormat.MatchString(text) {
+		return math.NaN()
+	}
+	number, _ := strconv.ParseFloat(text, 64)
+	return number
+}
+
+// xPathToBoolean converts a value to a boolean as the boolean() function does
+func xPathToBoolean(value interface{}) bool {
+	switch v := value.(type) {
+	case xPathItems:
+		return len(v) > 0
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	case bool:
+		return v
+	}
+	return value.(string) != ""
+}
@@ -0,0 +1,236 @@
+package dom
+
+import (
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// xPathFunction defines a function of the XPath core function library
+type xPathFunction struct {
+	minArguments     int
+	maxArguments     int // -1 for any number
+	result           xPathType
+	nodeSetArguments bool
+	call             func(context xPathContext, arguments []interface{}) interface{}
+}
+
+// xPathFunctionCall is the call of a function
+type xPathFunctionCall struct {
+	name      string
+	function  *xPathFunction
+	arguments []xPathExpr
+}
+
+func (e *xPathFunctionCall) evaluate(context xPathContext) interface{} {
+	arguments := make([]interface{}, len(e.arguments))
+	for i, argument := range e.arguments {
+		arguments[i] = argument.evaluate(context)
+	}
+	return e.function.call(context, arguments)
+}
+
+func (e *xPathFunctionCall) valueType() xPathType {
+	return e.function.result
+}
+
+var xPathFunctions = map[string]*xPathFunction{
+	// node-set functions
+	"last": {0, 0, xPathNumberType, false, func(context xPathContext, arguments []interface{}) interface{} {
+		return float64(context.size)
+	}},
+	"position": {0, 0, xPathNumberType, false, func(context xPathContext, arguments []interface{}) interface{} {
+		return float64(context.position)
+	}},
+	"count": {1, 1, xPathNumberType, true, func(context xPathContext, arguments []interface{}) interface{} {
+		return float64(len(arguments[0].(xPathItems)))
+	}},
+	"local-name": {0, 1, xPathString, true, func(context xPathContext, arguments []interface{}) interface{} {
+		if item, exists := xPathFunctionNode(context, arguments); exists {
+			_, local := SplitFQName(item.name())
+			return local
+		}
+		return ""
+	}},
+	"name": {0, 1, xPathString, true, func(context xPathContext, arguments []interface{}) interface{} {
+		if item, exists := xPathFunctionNode(context, arguments); exists {
+			return item.name()
+		}
+		return ""
+	}},
+	"namespace-uri": {0, 1, xPathString, true, func(context xPathContext, arguments []interface{}) interface{} {
+		if item, exists := xPathFunctionNode(context, arguments); exists {
+			return item.namespaceURI()
+		}
+		return ""
+	}},
+
+	// string functions
+	"string": {0, 1, xPathString, false, func(context xPathContext, arguments []interface{}) interface{} {
+		return xPathToString(xPathFunctionArgument(context, arguments))
+	}},
+	"concat": {2, -1, xPathString, false, func(context xPathContext, arguments []interface{}) interface{} {
+		result := strings.Builder{}
+		for _, argument := range arguments {
+			result.WriteString(xPathToString(argument))
+		}
+		return result.String()
+	}},
+	"starts-with": {2, 2, xPathBoolean, false, func(context xPathContext, arguments []interface{}) interface{} {
+		return strings.HasPrefix(xPathToString(arguments[0]), xPathToString(arguments[1]))
+	}},
+	"contains": {2, 2, xPathBoolean, false, func(context xPathContext, arguments []interface{}) interface{} {
+		return strings.Contains(xPathToString(arguments[0]), xPathToString(arguments[1]))
+	}},
+	"substring-before": {2, 2, xPathString, false, func(context xPathContext, arguments []interface{}) interface{} {
+		text, separator := xPathToString(arguments[0]), xPathToString(arguments[1])
+		if index := strings.Index(text, separator); index >= 0 {
+			return text[:index]
+		}
+		return ""
+	}},
+	"substring-after": {2, 2, xPathString, false, func(context xPathContext, arguments []interface{}) interface{} {
+		text, separator := xPathToString(arguments[0]), xPathToString(arguments[1])
+		if index := strings.Index(text, separator); index >= 0 {
+			return text[index+len(separator):]
+		}
+		return ""
+	}},
+	"substring": {2, 3, xPathString, false, func(context xPathContext, arguments []interface{}) interface{} {
+		// positions count characters starting at 1 and are rounded, NaN selects nothing
+		runes := []rune(xPathToString(arguments[0]))
+		start := xPathRound(xPathToNumber(arguments[1]))
+		end := math.Inf(1)
+		if len(arguments) == 3 {
+			end = start + xPathRound(xPathToNumber(arguments[2]))
+		}
+		result := strings.Builder{}
+		for i, r := range runes {
+			if position := float64(i + 1); position >= start && position < end {
+				result.WriteRune(r)
+			}
+		}
+		return result.String()
+	}},
+	"string-length": {0, 1, xPathNumberType, false, func(context xPathContext, arguments []interface{}) interface{} {
+		return float64(utf8.RuneCountInString(xPathToString(xPathFunctionArgument(context, arguments))))
+	}},
+	"normalize-space": {0, 1, xPathString, false, func(context xPathContext, arguments []interface{}) interface{} {
+		return strings.Join(strings.Fields(xPathToString(xPathFunctionArgument(context, arguments))), " ")
+	}},
+	"translate": {3, 3, xPathString, false, func(context xPathContext, arguments []interface{}) interface{} {
+		from, to := []rune(xPathToString(arguments[1])), []rune(xPathToString(arguments[2]))
+		return strings.Map(func(r rune) rune {
+			for i, f := range from {
+				if f == r {
+					if i < len(to) {
+						return to[i]
+					}
+					return -1
+				}
+			}
+			return r
+		}, xPathToString(arguments[0]))
+	}},
+
+	// boolean functions
+	"boolean": {1, 1, xPathBoolean, false, func(context xPathContext, arguments []interface{}) interface{} {
+		return xPathToBoolean(arguments[0])
+	}},
+	"not": {1, 1, xPathBoolean, false, func(context xPathContext, arguments []interface{}) interface{} {
+		return !xPathToBoolean(arguments[0])
+	}},
+	"true": {0, 0, xPathBoolean, false, func(context xPathContext, arguments []interface{}) interface{} {
+		return true
+	}},
+	"false": {0, 0, xPathBoolean, false, func(context xPathContext, arguments []interface{}) interface{} {
+		return false
+	}},
+	"lang": {1, 1, xPathBoolean, false, func(context xPathContext, arguments []interface{}) interface{} {
+		language := strings.ToLower(xPathToString(arguments[0]))
+		for _, item := range context.item.axis(xPathAxisAncestorOrSelf) {
+			if item.kind != xPathItemElement {
+				continue
+			}
+			for _, attr := range item.node.Attributes {
+				if attr.Name == "lang" && attr.Namespace != nil && attr.Namespace.Name == xmlNamespace {
+					value := strings.ToLower(attr.Value)
+					return value == language || strings.HasPrefix(value, language+"-")
+				}
+			}
+		}
+		return false
+	}},
+
+	// number functions
+	"number": {0, 1, xPathNumberType, false, func(context xPathContext, arguments []interface{}) interface{} {
+		return xPathToNumber(xPathFunctionArgument(context, arguments))
+	}},
+	"sum": {1, 1, xPathNumberType, true, func(context xPathContext, arguments []interface{}) interface{} {
+		sum := 0.0
+		for _, item := range arguments[0].(xPathItems) {
+			sum += xPathToNumber(item.stringValue())
+		}
+		return sum
+	}},
+	"floor": {1, 1, xPathNumberType, false, func(context xPathContext, arguments []interface{}) interface{} {
+		return math.Floor(xPathToNumber(arguments[0]))
+	}},
+	"ceiling": {1, 1, xPathNumberType, false, func(context xPathContext, arguments []interface{}) interface{} {
+		return math.Ceil(xPathToNumber(arguments[0]))
+	}},
+	"round": {1, 1, xPathNumberType, false, func(context xPathContext, arguments []interface{}) interface{} {
+		return xPathRound(xPathToNumber(arguments[0]))
+	}},
+}
+
+// xPathFunctionArgument returns the only argument, defaulting to the context node
+func xPathFunctionArgument(context xPathContext, arguments []interface{}) interface{} {
+	if len(arguments) == 0 {
+		return xPathItems{context.item}
+	}
+	return arguments[0]
+}
+
+// xPathFunctionNode returns the first node of the node-set argument, defaulting to the context node
+func xPathFunctionNode(context xPathContext, arguments []interface{}) (xPathItem, bool) {
+	items := xPathFunctionArgument(context, arguments).(xPathItems)
+	if len(items) == 0 {
+		return xPathItem{}, false
+	}
+	return items[0], true
+}
+
+// xPathRound rounds half up, as round() does
+func xPathRound(number float64) float64 {
+	if math.IsNaN(number) || math.IsInf(number, 0) {
+		return number
+	}
+	return math.Floor(number + 0.5)
+}
+
+// name returns the qualified name of the item, empty for text and the document
+func (i xPathItem) name() string {
+	switch i.kind {
+	case xPathItemElement:
+		if i.node.Namespace != nil && i.node.Namespace.Abbreviation != "" {
+			return i.node.Namespace.Abbreviation + ":" + i.node.Name
+		}
+		return i.node.Name
+	case xPathItemAttribute:
+		if i.attribute.Namespace != nil && i.attribute.Namespace.Abbreviation != "" {
+			return i.attribute.Namespace.Abbreviation + ":" + i.attribute.Name
+		}
+		return i.attribute.Name
+	}
+	return ""
+}
+
+// namespaceURI returns the namespace of the item
+func (i xPathItem) namespaceURI() string {
+	if i.kind != xPathItemElement && i.kind != xPathItemAttribute {
+		return ""
+	}
+	_, namespace := i.expandedName()
+	return namespace
+}
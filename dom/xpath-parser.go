@@ -0,0 +1,590 @@
+package dom
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// xPathAxis defines the direction of a location step
+type xPathAxis int
+
+const (
+	xPathAxisChild xPathAxis = iota
+	xPathAxisDescendant
+	xPathAxisDescendantOrSelf
+	xPathAxisParent
+	xPathAxisAncestor
+	xPathAxisAncestorOrSelf
+	xPathAxisFollowingSibling
+	xPathAxisPrecedingSibling
+	xPathAxisFollowing
+	xPathAxisPreceding
+	xPathAxisAttribute
+	xPathAxisSelf
+)
+
+var xPathAxes = map[string]xPathAxis{
+	"child":              xPathAxisChild,
+	"descendant":         xPathAxisDescendant,
+	"descendant-or-self": xPathAxisDescendantOrSelf,
+	"parent":             xPathAxisParent,
+	"ancestor":           xPathAxisAncestor,
+	"ancestor-or-self":   xPathAxisAncestorOrSelf,
+	"following-sibling":  xPathAxisFollowingSibling,
+	"preceding-sibling":  xPathAxisPrecedingSibling,
+	"following":          xPathAxisFollowing,
+	"preceding":          xPathAxisPreceding,
+	"attribute":          xPathAxisAttribute,
+	"self":               xPathAxisSelf,
+}
+
+// reverse returns true for axes listing their nodes in reverse document order
+func (a xPathAxis) reverse() bool {
+	switch a {
+	case xPathAxisParent, xPathAxisAncestor, xPathAxisAncestorOrSelf, xPathAxisPrecedingSibling, xPathAxisPreceding:
+		return true
+	}
+	return false
+}
+
+// xPathNodeType defines the kind of a node test
+type xPathNodeType int
+
+const (
+	xPathNodeTypeName xPathNodeType = iota
+	xPathNodeTypeNode
+	xPathNodeTypeText
+	xPathNodeTypeComment
+	xPathNodeTypeProcessingInstruction
+)
+
+var xPathNodeTypes = map[string]xPathNodeType{
+	"node":                   xPathNodeTypeNode,
+	"text":                   xPathNodeTypeText,
+	"comment":                xPathNodeTypeComment,
+	"processing-instruction": xPathNodeTypeProcessingInstruction,
+}
+
+// xPathStep is a single location step: axis::test[predicate]...
+type xPathStep struct {
+	axis       xPathAxis
+	nodeType   xPathNodeType
+	name       string
+	predicates []xPathExpr
+
+	// namespace aware name tests match the local name and the namespace bound to the prefix
+	namespaceAware bool
+	prefixed       bool
+	namespace      string
+}
+
+// xPathLocation is a parsed location path
+type xPathLocation struct {
+	absolute bool
+	steps    []*xPathStep
+}
+
+// xPathParser reads an expression; with namespaces set, name tests are namespace aware
+type xPathParser struct {
+	expression string
+	position   int
+	namespaces map[string]string
+}
+
+// parseXPath parses the given XPath expression, namespaces binds the prefixes for namespace aware name tests
+func parseXPath(expression string, namespaces map[string]string) (xPathExpr, error) {
+	p := &xPathParser{expression: expression, namespaces: namespaces}
+
+	// for convenience, the empty path selects the context node
+	if p.skipWhitespace(); p.position == len(p.expression) {
+		return &xPathLocation{}, nil
+	}
+
+	expr, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipWhitespace(); p.position < len(p.expression) {
+		return nil, p.errorf("unexpected [%s]", p.expression[p.position:])
+	}
+	return expr, nil
+}
+
+func (p *xPathParser) errorf(format string, arguments ...interface{}) error {
+	return fmt.Errorf("XPath(): invalid expression [%s] at %d: %s", p.expression, p.position, fmt.Sprintf(format, arguments...))
+}
+
+func (p *xPathParser) skipWhitespace() {
+	for p.position < len(p.expression) && strings.ContainsRune(" \t\r\n", rune(p.expression[p.position])) {
+		p.position++
+	}
+}
+
+// consume skips the given token if it is next
+func (p *xPathParser) consume(token string) bool {
+	p.skipWhitespace()
+	if strings.HasPrefix(p.expression[p.position:], token) {
+		p.position += len(token)
+		return true
+	}
+	return false
+}
+
+// peek checks if the given token is next without consuming it
+func (p *xPathParser) peek(token string) bool {
+	p.skipWhitespace()
+	return strings.HasPrefix(p.expression[p.position:], token)
+}
+
+// consumeKeyword skips the given operator name if it is next
+func (p *xPathParser) consumeKeyword(keyword string) bool {
+	p.skipWhitespace()
+	end := p.position + len(keyword)
+	if !strings.HasPrefix(p.expression[p.position:], keyword) ||
+		(end < len(p.expression) && isXPathNameChar(rune(p.expression[end]))) {
+		return false
+	}
+	p.position = end
+	return true
+}
+
+// parseExpr reads an expression: or, and, equality, relational, additive, multiplicative, unary, union, path
+func (p *xPathParser) parseExpr() (xPathExpr, error) {
+	return p.parseBinary(0)
+}
+
+// xPathOperators lists the binary operators by precedence, lowest first
+var xPathOperators = [][]string{
+	{"or"},
+	{"and"},
+	{"=", "!="},
+	{"<=", ">=", "<", ">"},
+	{"+", "-"},
+	{"*", "div", "mod"},
+}
+
+func (p *xPathParser) parseBinary(level int) (xPathExpr, error) {
+	if level == len(xPathOperators) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		operator := p.parseOperator(xPathOperators[level])
+		if operator == "" {
+			return left, nil
+		}
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &xPathBinary{operator: operator, left: left, right: right}
+	}
+}
+
+// parseOperator consumes one of the given operators
+func (p *xPathParser) parseOperator(operators []string) string {
+	for _, operator := range operators {
+		if isXPathNameStart(rune(operator[0])) {
+			if p.consumeKeyword(operator) {
+				return operator
+			}
+		} else if p.consume(operator) {
+			return operator
+		}
+	}
+	return ""
+}
+
+func (p *xPathParser) parseUnary() (xPathExpr, error) {
+	if p.consume("-") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &xPathNegation{operand: operand}, nil
+	}
+
+	left, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("|") {
+		right, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		if left.valueType() != xPathNodeSet || right.valueType() != xPathNodeSet {
+			return nil, p.errorf("| requires node-sets")
+		}
+		left = &xPathUnion{left: left, right: right}
+	}
+	return left, nil
+}
+
+// parsePath reads a location path, or a filter expression optionally followed by a relative location path
+func (p *xPathParser) parsePath() (xPathExpr, error) {
+	if !p.startsPrimary() {
+		return p.parseLocationPath()
+	}
+
+	primary, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	filter := &xPathFilter{primary: primary}
+	for p.peek("[") {
+		predicate, err := p.parsePredicate()
+		if err != nil {
+			return nil, err
+		}
+		filter.predicates = append(filter.predicates, predicate)
+	}
+
+	if p.peek("/") {
+		location := &xPathLocation{}
+		if p.consume("//") {
+			location.steps = append(location.steps, &xPathStep{axis: xPathAxisDescendantOrSelf, nodeType: xPathNodeTypeNode})
+		} else {
+			p.consume("/")
+		}
+		if err := p.parseRelativeLocationPath(location); err != nil {
+			return nil, err
+		}
+		filter.location = location
+	}
+
+	if len(filter.predicates) == 0 && filter.location == nil {
+		return primary, nil
+	}
+	if primary.valueType() != xPathNodeSet {
+		return nil, p.errorf("predicates and paths require a node-set")
+	}
+	return filter, nil
+}
+
+// startsPrimary checks if a primary expression (and not a location path) follows
+func (p *xPathParser) startsPrimary() bool {
+	p.skipWhitespace()
+	if p.position >= len(p.expression) {
+		return false
+	}
+	next := p.expression[p.position]
+	switch {
+	case next == '(' || next == '\'' || next == '"' || next == '$' || (next >= '0' && next <= '9'):
+		return true
+	case next == '.':
+		return p.position+1 < len(p.expression) && p.expression[p.position+1] >= '0' && p.expression[p.position+1] <= '9'
+	}
+
+	// function calls, but not node type tests
+	start := p.position
+	defer func() { p.position = start }()
+	name := p.parseName()
+	if name == "" {
+		return false
+	}
+	if _, exists := xPathNodeTypes[name]; exists {
+		return false
+	}
+	return p.peek("(")
+}
+
+func (p *xPathParser) parsePrimary() (xPathExpr, error) {
+	p.skipWhitespace()
+	switch next := p.expression[p.position]; {
+	case next == '(':
+		p.position++
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(")") {
+			return nil, p.errorf("missing )")
+		}
+		return expr, nil
+
+	case next == '\'' || next == '"':
+		literal, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		return xPathLiteral(xPathUnescape(literal)), nil
+
+	case next == '$':
+		return nil, p.errorf("variables are not supported")
+
+	case next == '.' || (next >= '0' && next <= '9'):
+		start := p.position
+		for p.position < len(p.expression) && (p.expression[p.position] == '.' ||
+			(p.expression[p.position] >= '0' && p.expression[p.position] <= '9')) {
+			p.position++
+		}
+		number, err := strconv.ParseFloat(p.expression[start:p.position], 64)
+		if err != nil {
+			return nil, p.errorf("invalid number [%s]", p.expression[start:p.position])
+		}
+		return xPathNumber(number), nil
+	}
+
+	return p.parseFunctionCall()
+}
+
+func (p *xPathParser) parseFunctionCall() (xPathExpr, error) {
+	name := p.parseName()
+	function, exists := xPathFunctions[name]
+	if !exists {
+		return nil, p.errorf("unknown function [%s]", name)
+	}
+	p.consume("(")
+
+	call := &xPathFunctionCall{name: name, function: function}
+	if !p.consume(")") {
+		for {
+			argument, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			call.arguments = append(call.arguments, argument)
+			if p.consume(")") {
+				break
+			}
+			if !p.consume(",") {
+				return nil, p.errorf("missing ) after arguments of %s()", name)
+			}
+		}
+	}
+
+	if len(call.arguments) < function.minArguments || (function.maxArguments >= 0 && len(call.arguments) > function.maxArguments) {
+		return nil, p.errorf("wrong number of arguments for %s()", name)
+	}
+	if function.nodeSetArguments {
+		for _, argument := range call.arguments {
+			if argument.valueType() != xPathNodeSet {
+				return nil, p.errorf("%s() requires a node-set", name)
+			}
+		}
+	}
+	return call, nil
+}
+
+func (p *xPathParser) parseLocationPath() (*xPathLocation, error) {
+	location := &xPathLocation{}
+	if p.consume("//") {
+		location.absolute = true
+		location.steps = append(location.steps, &xPathStep{axis: xPathAxisDescendantOrSelf, nodeType: xPathNodeTypeNode})
+	} else if p.consume("/") {
+		location.absolute = true
+		// the root alone
+		if p.skipWhitespace(); p.position == len(p.expression) || !p.startsStep() {
+			return location, nil
+		}
+	}
+
+	if err := p.parseRelativeLocationPath(location); err != nil {
+		return nil, err
+	}
+	return location, nil
+}
+
+func (p *xPathParser) parseRelativeLocationPath(location *xPathLocation) error {
+	for {
+		step, err := p.parseStep()
+		if err != nil {
+			return err
+		}
+		location.steps = append(location.steps, step)
+
+		if p.consume("//") {
+			location.steps = append(location.steps, &xPathStep{axis: xPathAxisDescendantOrSelf, nodeType: xPathNodeTypeNode})
+		} else if !p.consume("/") {
+			break
+		}
+	}
+	location.optimize()
+	return nil
+}
+
+// startsWithName checks if the relative path starts with a child step for the given name
+func (l *xPathLocation) startsWithName(name string) bool {
+	return !l.absolute && len(l.steps) > 0 && l.steps[0].axis == xPathAxisChild &&
+		l.steps[0].nodeType == xPathNodeTypeName && l.steps[0].name == name
+}
+
+// optimize replaces descendant-or-self::node()/child::x by descendant::x, which is equal without predicates
+func (l *xPathLocation) optimize() {
+	steps := []*xPathStep{}
+	for i := 0; i < len(l.steps); i++ {
+		step := l.steps[i]
+		if step.axis == xPathAxisDescendantOrSelf && step.nodeType == xPathNodeTypeNode && len(step.predicates) == 0 &&
+			i+1 < len(l.steps) && l.steps[i+1].axis == xPathAxisChild && len(l.steps[i+1].predicates) == 0 {
+			next := *l.steps[i+1]
+			next.axis = xPathAxisDescendant
+			steps = append(steps, &next)
+			i++
+			continue
+		}
+		steps = append(steps, step)
+	}
+	l.steps = steps
+}
+
+// startsStep checks if a location step follows
+func (p *xPathParser) startsStep() bool {
+	if p.position >= len(p.expression) {
+		return false
+	}
+	next := rune(p.expression[p.position])
+	return next == '.' || next == '@' || next == '*' || isXPathNameStart(next)
+}
+
+func (p *xPathParser) parseStep() (*xPathStep, error) {
+	// abbreviated steps
+	if p.consume("..") {
+		return &xPathStep{axis: xPathAxisParent, nodeType: xPathNodeTypeNode}, nil
+	}
+	if p.consume(".") {
+		return &xPathStep{axis: xPathAxisSelf, nodeType: xPathNodeTypeNode}, nil
+	}
+
+	step := &xPathStep{axis: xPathAxisChild}
+	if p.consume("@") {
+		step.axis = xPathAxisAttribute
+	} else {
+		start := p.position
+		name := p.parseName()
+		if name != "" && p.consume("::") {
+			axis, exists := xPathAxes[name]
+			if !exists {
+				return nil, p.errorf("unsupported axis [%s]", name)
+			}
+			step.axis = axis
+		} else {
+			p.position = start
+		}
+	}
+
+	if err := p.parseNodeTest(step); err != nil {
+		return nil, err
+	}
+
+	// predicates
+	for p.peek("[") {
+		predicate, err := p.parsePredicate()
+		if err != nil {
+			return nil, err
+		}
+		step.predicates = append(step.predicates, predicate)
+	}
+	return step, nil
+}
+
+func (p *xPathParser) parseNodeTest(step *xPathStep) error {
+	p.skipWhitespace()
+	step.namespaceAware = p.namespaces != nil
+	if p.consume("*") {
+		step.name = "*"
+		return nil
+	}
+
+	name := p.parseName()
+	if name == "" {
+		return p.errorf("node test expected")
+	}
+
+	// prefix:* or prefix:name
+	if p.position < len(p.expression)-1 && p.expression[p.position] == ':' && p.expression[p.position+1] != ':' {
+		p.position++
+		local := "*"
+		if !p.consume("*") {
+			if local = p.parseName(); local == "" {
+				return p.errorf("local name expected")
+			}
+			local = xPathUnescape(local)
+		}
+		if !step.namespaceAware {
+			step.name = name + ":" + local
+			return nil
+		}
+
+		namespace, exists := p.namespaces[name]
+		if !exists {
+			return p.errorf("unbound namespace prefix [%s]", name)
+		}
+		step.name, step.prefixed, step.namespace = local, true, namespace
+		return nil
+	}
+
+	// node type tests
+	if nodeType, exists := xPathNodeTypes[name]; exists && p.consume("(") {
+		step.nodeType = nodeType
+		if nodeType == xPathNodeTypeProcessingInstruction && (p.peek("'") || p.peek(`"`)) {
+			if _, err := p.parseLiteral(); err != nil {
+				return err
+			}
+		}
+		if !p.consume(")") {
+			return p.errorf("missing ) after %s(", name)
+		}
+		return nil
+	}
+
+	step.name = xPathUnescape(name)
+	return nil
+}
+
+// parseName reads a NCName, taking escaped arguments as a whole
+func (p *xPathParser) parseName() string {
+	p.skipWhitespace()
+	start := p.position
+	for p.position < len(p.expression) {
+		if strings.HasPrefix(p.expression[p.position:], xPathArgumentStart) {
+			if end := strings.Index(p.expression[p.position:], xPathArgumentEnd); end != -1 {
+				p.position += end + len(xPathArgumentEnd)
+				continue
+			}
+		}
+		next := rune(p.expression[p.position])
+		if (p.position == start && !isXPathNameStart(next)) || !isXPathNameChar(next) {
+			break
+		}
+		p.position++
+	}
+	return p.expression[start:p.position]
+}
+
+func (p *xPathParser) parseLiteral() (string, error) {
+	p.skipWhitespace()
+	quote := p.expression[p.position]
+	end := strings.IndexByte(p.expression[p.position+1:], quote)
+	if end == -1 {
+		return "", p.errorf("unterminated literal")
+	}
+	literal := p.expression[p.position+1 : p.position+1+end]
+	p.position += end + 2
+	return literal, nil
+}
+
+func (p *xPathParser) parsePredicate() (xPathExpr, error) {
+	p.consume("[")
+	predicate, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.consume("]") {
+		return nil, p.errorf("missing ]")
+	}
+	return predicate, nil
+}
+
+func isXPathNameStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_' || r >= 0x80
+}
+
+func isXPathNameChar(r rune) bool {
+	return isXPathNameStart(r) || unicode.IsDigit(r) || r == '-' || r == '.'
+}
@@ -0,0 +1,145 @@
+package dom
+
+import (
+	"container/list"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// xPathCacheSize is the number of compiled expressions kept for Node.XPath and friends
+const xPathCacheSize = 512
+
+// XPath is a compiled XPath expression, safe for concurrent use
+type XPath struct {
+	expression     string
+	namespaceAware bool
+	expr           xPathExpr
+}
+
+// CompileXPath compiles the given XPath expression, names are matched like Node.XPath does
+func CompileXPath(expression string) (*XPath, error) {
+	return compileXPath(expression, nil)
+}
+
+// CompileXPathNS compiles the given XPath expression with namespace aware name tests, see Node.XPathNS
+func CompileXPathNS(expression string, namespaces map[string]string) (*XPath, error) {
+	if namespaces == nil {
+		namespaces = map[string]string{}
+	}
+	return compileXPath(expression, namespaces)
+}
+
+// MustCompileXPath compiles the given XPath expression, panicking if it is invalid
+func MustCompileXPath(expression string) *XPath {
+	compiled, err := CompileXPath(expression)
+	if err != nil {
+		panic(err)
+	}
+	return compiled
+}
+
+func compileXPath(expression string, namespaces map[string]string) (*XPath, error) {
+	expr, err := parseXPath(expression, namespaces)
+	if err != nil {
+		return nil, err
+	}
+	return &XPath{expression: expression, namespaceAware: namespaces != nil, expr: expr}, nil
+}
+
+// String returns the source of the expression
+func (x *XPath) String() string {
+	return x.expression
+}
+
+// SelectsNodes returns true if the expression results in a node-set
+func (x *XPath) SelectsNodes() bool {
+	return x.expr.valueType() == xPathNodeSet
+}
+
+// Select returns the Nodes selected by the expression with node as context, in document order.
+// Expressions not selecting nodes return an empty list, see Eval
+func (x *XPath) Select(node *Node) *NodeList {
+	if !node.Exists || !x.SelectsNodes() {
+		return &NodeList{}
+	}
+	return node.evaluateXPath(x.expr, !x.namespaceAware, false).(xPathItems).nodeList()
+}
+
+// Eval evaluates the expression with node as context, returning a string, float64, bool or *NodeList
+func (x *XPath) Eval(node *Node) interface{} {
+	result := node.evaluateXPath(x.expr, !x.namespaceAware, false)
+	if items, ok := result.(xPathItems); ok {
+		return items.nodeList()
+	}
+	return result
+}
+
+// xPathCache keeps the most recently used compiled expressions
+type xPathCache struct {
+	mutex   sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	size    int
+}
+
+type xPathCacheEntry struct {
+	key      string
+	compiled *XPath
+}
+
+var xPathCompiled = &xPathCache{
+	entries: map[string]*list.Element{},
+	order:   list.New(),
+	size:    xPathCacheSize,
+}
+
+// compile returns the compiled expression from the cache, compiling it if necessary
+func (c *xPathCache) compile(expression string, namespaces map[string]string) (*XPath, error) {
+	key := xPathCacheKey(expression, namespaces)
+
+	c.mutex.Lock()
+	if element, exists := c.entries[key]; exists {
+		c.order.MoveToFront(element)
+		c.mutex.Unlock()
+		return element.Value.(*xPathCacheEntry).compiled, nil
+	}
+	c.mutex.Unlock()
+
+	compiled, err := compileXPath(expression, namespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, exists := c.entries[key]; !exists {
+		c.entries[key] = c.order.PushFront(&xPathCacheEntry{key: key, compiled: compiled})
+		if c.order.Len() > c.size {
+			oldest := c.order.Back()
+			c.order.Remove(oldest)
+			delete(c.entries, oldest.Value.(*xPathCacheEntry).key)
+		}
+	}
+	return compiled, nil
+}
+
+// xPathCacheKey combines the expression and the namespace bindings (nil and empty differ)
+func xPathCacheKey(expression string, namespaces map[string]string) string {
+	if namespaces == nil {
+		return expression
+	}
+	prefixes := make([]string, 0, len(namespaces))
+	for prefix := range namespaces {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	key := strings.Builder{}
+	key.WriteString(expression)
+	key.WriteString("\x00ns")
+	for _, prefix := range prefixes {
+		key.WriteString("\x00" + prefix + "=" + namespaces[prefix])
+	}
+	return key.String()
+}
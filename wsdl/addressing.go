@@ -0,0 +1,203 @@
+package wsdl
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+
+	"github.com/lordkhonsu/go-soap/dom"
+)
+
+const (
+	wsaNS        = "http://www.w3.org/2005/08/addressing"
+	wsaAnonymous = "http://www.w3.org/2005/08/addressing/anonymous"
+	wsawNS       = "http://www.w3.org/2006/05/addressing/wsdl"
+	wsamNS       = "http://www.w3.org/2007/05/addressing/metadata"
+)
+
+// AddressingMode defines whether WS-Addressing headers are sent
+type AddressingMode int
+
+const (
+	// AddressingAuto sends WS-Addressing headers if the binding declares wsaw:UsingAddressing (or a wsam:Addressing policy)
+	AddressingAuto AddressingMode = iota
+	// AddressingEnabled always sends WS-Addressing headers
+	AddressingEnabled
+	// AddressingDisabled never sends WS-Addressing headers
+	AddressingDisabled
+)
+
+// WithAddressing sets whether WS-Addressing 1.0 headers are sent, detected from the WSDL by default
+func WithAddressing(mode AddressingMode) Option {
+	return func(c *Client) {
+		c.addressing = mode
+	}
+}
+
+// usesAddressing checks the binding (and the port) for a WS-Addressing declaration, directly or in their policies
+func (w *WSDL) usesAddressing(nodes ...*dom.Node) bool {
+	visited := map[*dom.Node]bool{}
+	for _, node := range nodes {
+		for _, child := range node.Children.All() {
+			switch child.Name {
+			case "UsingAddressing":
+				if child.Namespace != nil && child.Namespace.Name == wsawNS {
+					return true
+				}
+			case "Policy":
+				if w.policyUsesAddressing(child, visited) {
+					return true
+				}
+			case "PolicyReference":
+				if policy := w.lookupPolicy(child.GetAttributeValue("URI")); policy.Exists && w.policyUsesAddressing(policy, visited) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// policyUsesAddressing searches a <wsp:Policy> and the policies it references for a wsam:Addressing assertion,
+// which may be nested in ExactlyOne / All operators
+func (w *WSDL) policyUsesAddressing(policy *dom.Node, visited map[*dom.Node]bool) bool {
+	if visited[policy] {
+		return false
+	}
+	visited[policy] = true
+
+	for _, declaration := range policy.XPath(".//Addressing").All() {
+		if declaration.Namespace != nil && declaration.Namespace.Name == wsamNS {
+			return true
+		}
+	}
+	for _, reference := range policy.XPath(".//PolicyReference").All() {
+		if referenced := w.lookupPolicy(reference.GetAttributeValue("URI")); referenced.Exists && w.policyUsesAddressing(referenced, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupPolicy returns the <wsp:Policy> referenced by uri: "#id" refers to its (wsu:)Id, anything else to its Name
+func (w *WSDL) lookupPolicy(uri string) *dom.Node {
+	for _, definitions := range w.definitions {
+		for _, policy := range definitions.XPath(".//Policy").All() {
+			if strings.HasPrefix(uri, "#") && policy.GetAttributeValue("Id") == uri[1:] {
+				return policy
+			}
+			if uri != "" && policy.GetAttributeValue("Name") == uri {
+				return policy
+			}
+		}
+	}
+	return &dom.Node{}
+}
+
+// UsesAddressing returns whether requests of this Operation carry WS-Addressing headers by default
+func (o *Operation) UsesAddressing() bool {
+	return o.addressing
+}
+
+// addressingAction returns the WS-Addressing action of the Operation's input: the explicit wsam:Action
+// (or wsaw:Action) of the portType, otherwise the default action pattern; the SOAP action does not apply
+func (o *Operation) addressingAction() string {
+	input := o.portTypeNode.XPath("input").First()
+	for _, attr := range input.Attributes {
+		if attr.Name == "Action" && attr.Namespace != nil && (attr.Namespace.Name == wsamNS || attr.Namespace.Name == wsawNS) {
+			return attr.Value
+		}
+	}
+
+	// [target namespace][delimiter][port type name][delimiter][input name], the namespace being the one of the
+	// <definitions> declaring the portType, which may be imported
+	targetNamespace := ""
+	for node := o.portTypeNode; node != nil; node = node.Parent {
+		if node.Name == "definitions" {
+			targetNamespace = node.GetAttributeValue("targetNamespace")
+			break
+		}
+	}
+	delimiter := "/"
+	if strings.HasPrefix(targetNamespace, "urn:") {
+		delimiter = ":"
+	}
+	inputName := input.GetAttributeValue("name")
+	if inputName == "" {
+		inputName = o.name + "Request"
+	}
+	portTypeName := ""
+	if o.portTypeNode.Parent != nil {
+		portTypeName = o.portTypeNode.Parent.GetAttributeValue("name")
+	}
+	return strings.TrimSuffix(targetNamespace, delimiter) + delimiter + portTypeName + delimiter + inputName
+}
+
+// SetAddressing enables or disables WS-Addressing headers for this Request
+func (r *Request) SetAddressing(enabled bool) {
+	r.addressing = enabled
+}
+
+// SetMessageID sets the wsa:MessageID of this Request, a random UUID URN is used otherwise
+func (r *Request) SetMessageID(messageID string) {
+	r.messageID = messageID
+}
+
+// MessageID returns the wsa:MessageID of this Request, empty until the Request was built.
+// A Request sent twice keeps its MessageID
+func (r *Request) MessageID() string {
+	return r.messageID
+}
+
+// SetReplyTo sets the wsa:ReplyTo address of this Request, defaults to the anonymous address
+func (r *Request) SetReplyTo(address string) {
+	r.replyTo = address
+}
+
+func (r *Request) buildAddressing() error {
+	if r.messageID == "" {
+		messageID, err := newUUID()
+		if err != nil {
+			return err
+		}
+		r.messageID = "urn:uuid:" + messageID
+	}
+	replyTo := r.replyTo
+	if replyTo == "" {
+		replyTo = wsaAnonymous
+	}
+
+	r.rootNode.RegisterNS(wsaNS, "wsa")
+	mustUnderstand := r.operation.soapVersion.EnvelopeNamespace()
+
+	action := r.header.NewChildren("Action", wsaNS)
+	action.SetAttribute(mustUnderstand, "mustUnderstand", "1")
+	action.SetValue(r.operation.addressingAction())
+	r.header.NewChildren("MessageID", wsaNS).SetValue(r.messageID)
+	r.header.NewChildren("ReplyTo", wsaNS).NewChildren("Address", wsaNS).SetValue(replyTo)
+	to := r.header.NewChildren("To", wsaNS)
+	to.SetAttribute(mustUnderstand, "mustUnderstand", "1")
+	to.SetValue(r.operation.url)
+	return nil
+}
+
+// RelatesTo returns the wsa:RelatesTo of the Response, which is the MessageID of the Request it answers
+func (r *Response) RelatesTo() string {
+	for _, relatesTo := range r.Header().XPath("RelatesTo").All() {
+		if relatesTo.Namespace != nil && relatesTo.Namespace.Name == wsaNS {
+			return strings.TrimSpace(relatesTo.String())
+		}
+	}
+	return ""
+}
+
+// newUUID returns a random (version 4) UUID
+func newUUID() (string, error) {
+	uuid := make([]byte, 16)
+	if _, err := rand.Read(uuid); err != nil {
+		return "", err
+	}
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:]), nil
+}
@@ -0,0 +1,164 @@
+package wsdl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lordkhonsu/go-soap/dom"
+)
+
+const (
+	testPolicyNamespaces = `xmlns:wsp="http://www.w3.org/ns/ws-policy" xmlns:wsam="` + wsamNS + `"` +
+		` xmlns:wsu="` + wsuNS + `"`
+	testAddressingPolicy = `<wsp:ExactlyOne><wsp:All><wsam:Addressing><wsp:Policy/></wsam:Addressing></wsp:All></wsp:ExactlyOne>`
+)
+
+// newAddressingTestClient returns a client for the test WSDL, extending its binding, definitions and portType input
+func newAddressingTestClient(t *testing.T, binding string, definitions string, input string) *Client {
+	t.Helper()
+	wsdl := strings.NewReplacer(
+		`<soap:binding transport="http://schemas.xmlsoap.org/soap/http"/>`,
+		`<soap:binding transport="http://schemas.xmlsoap.org/soap/http"/>`+binding,
+		`<wsdl:binding `, definitions+`<wsdl:binding `,
+		`<wsdl:input message="tns:CallIn"/>`, `<wsdl:input message="tns:CallIn" `+input+`/>`,
+	).Replace(testDefinitions(testSchema("", `<xs:element name="Request" type="xs:string"/>`)))
+
+	client, err := NewClientFromReader(strings.NewReader(wsdl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestAddressingDetection(t *testing.T) {
+	tests := []struct {
+		name        string
+		binding     string
+		definitions string
+		expected    bool
+	}{
+		{"none", "", "", false},
+		{"using addressing", `<wsaw:UsingAddressing xmlns:wsaw="` + wsawNS + `"/>`, "", true},
+		{"embedded policy", `<wsp:Policy ` + testPolicyNamespaces + `>` + testAddressingPolicy + `</wsp:Policy>`, "", true},
+		{"policy reference",
+			`<wsp:PolicyReference xmlns:wsp="http://www.w3.org/ns/ws-policy" URI="#AddressingPolicy"/>`,
+			`<wsp:Policy wsu:Id="AddressingPolicy" ` + testPolicyNamespaces + `>` + testAddressingPolicy + `</wsp:Policy>`,
+			true},
+		{"nested policy reference",
+			`<wsp:PolicyReference xmlns:wsp="http://www.w3.org/ns/ws-policy" URI="#BindingPolicy"/>`,
+			`<wsp:Policy wsu:Id="BindingPolicy" ` + testPolicyNamespaces + `><wsp:ExactlyOne><wsp:All>` +
+				`<wsp:PolicyReference URI="http://example.com/policies/addressing"/></wsp:All></wsp:ExactlyOne></wsp:Policy>` +
+				`<wsp:Policy Name="http://example.com/policies/addressing" ` + testPolicyNamespaces + `>` + testAddressingPolicy + `</wsp:Policy>`,
+			true},
+		{"policy without addressing",
+			`<wsp:PolicyReference xmlns:wsp="http://www.w3.org/ns/ws-policy" URI="#OtherPolicy"/>`,
+			`<wsp:Policy wsu:Id="OtherPolicy" ` + testPolicyNamespaces + `><wsp:ExactlyOne><wsp:All/></wsp:ExactlyOne></wsp:Policy>` +
+				`<wsp:Policy wsu:Id="AddressingPolicy" ` + testPolicyNamespaces + `>` + testAddressingPolicy + `</wsp:Policy>`,
+			false},
+		{"unresolved policy reference", `<wsp:PolicyReference xmlns:wsp="http://www.w3.org/ns/ws-policy" URI="#Missing"/>`, "", false},
+		{"cyclic policy reference",
+			`<wsp:PolicyReference xmlns:wsp="http://www.w3.org/ns/ws-policy" URI="#CyclicPolicy"/>`,
+			`<wsp:Policy wsu:Id="CyclicPolicy" ` + testPolicyNamespaces + `><wsp:PolicyReference URI="#CyclicPolicy"/></wsp:Policy>`,
+			false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := newAddressingTestClient(t, test.binding, test.definitions, "")
+			operation, err := client.Service("TestService").LookupOperation("Call")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if operation.UsesAddressing() != test.expected {
+				t.Fatalf("expected UsesAddressing() = %v", test.expected)
+			}
+		})
+	}
+}
+
+func TestAddressingAction(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		// the soapAction of the binding must not be used
+		{"default pattern", "", testNamespace + "/TestPort/CallRequest"},
+		{"named input", `name="CallInput"`, testNamespace + "/TestPort/CallInput"},
+		{"explicit action", `xmlns:wsam="` + wsamNS + `" wsam:Action="urn:explicit"`, "urn:explicit"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := newAddressingTestClient(t, `<wsaw:UsingAddressing xmlns:wsaw="`+wsawNS+`"/>`, "", test.input)
+			request := newTestRequest(t, client)
+			data, err := request.XMLE()
+			if err != nil {
+				t.Fatal(err)
+			}
+			envelope, err := dom.Parse([]byte(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if action := envelope.Root.XPath("Header/Action").First().String(); action != test.expected {
+				t.Fatalf("expected action [%s], got [%s]", test.expected, action)
+			}
+			if messageID := envelope.Root.XPath("Header/MessageID").First().String(); messageID != request.MessageID() {
+				t.Fatalf("expected MessageID [%s], got [%s]", request.MessageID(), messageID)
+			}
+		})
+	}
+}
+
+// testImportingDefinitions declares a service in its own namespace for the portType of the imported test WSDL
+const testImportingDefinitions = `<wsdl:definitions name="Root" targetNamespace="urn:root"
+  xmlns:wsdl="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+  xmlns:tns="` + testNamespace + `"
+  xmlns:root="urn:root">
+  <wsdl:import namespace="` + testNamespace + `" location="port.wsdl"/>
+  <wsdl:binding name="RootBinding" type="tns:TestPort">
+    <soap:binding transport="http://schemas.xmlsoap.org/soap/http"/>
+    <wsaw:UsingAddressing xmlns:wsaw="` + wsawNS + `"/>
+    <wsdl:operation name="Call">
+      <soap:operation soapAction="urn:root:Call"/>
+      <wsdl:input><soap:body use="literal"/></wsdl:input>
+      <wsdl:output><soap:body use="literal"/></wsdl:output>
+    </wsdl:operation>
+  </wsdl:binding>
+  <wsdl:service name="RootService">
+    <wsdl:port name="RootPort" binding="root:RootBinding">
+      <soap:address location="http://localhost/root"/>
+    </wsdl:port>
+  </wsdl:service>
+</wsdl:definitions>`
+
+func TestAddressingActionImported(t *testing.T) {
+	dir := t.TempDir()
+	imported := testDefinitions(testSchema("", `<xs:element name="Request" type="xs:string"/>`))
+	if err := os.WriteFile(filepath.Join(dir, "port.wsdl"), []byte(imported), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClientFromReader(strings.NewReader(testImportingDefinitions), WithBaseLocation(filepath.Join(dir, "root.wsdl")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	operation, err := client.Service("RootService").LookupOperation("Call")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := operation.NewRequest().XMLE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope, err := dom.Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the default action is built from the namespace of the portType, not the one of the importing WSDL
+	expected := testNamespace + "/TestPort/CallRequest"
+	if action := envelope.Root.XPath("Header/Action").First().String(); action != expected {
+		t.Fatalf("expected action [%s], got [%s]", expected, action)
+	}
+}
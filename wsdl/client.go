@@ -1,18 +1,34 @@
 package wsdl
 
 import (
-	"bytes"
-	"encoding/xml"
+	"context"
+	"crypto/tls"
 	"fmt"
-	"github.com/lordkhonsu/go-soap/dom"
+	"io"
+	"io/fs"
 	"io/ioutil"
 	"net/http"
 )
 
 // Client defines a WSDL parsing client, providing SOAP functions and entities
 type Client struct {
-	url             string
-	httpClient      *http.Client
+	url               string
+	httpClient        *http.Client
+	httpHeaders       http.Header
+	userAgent         string
+	tlsConfig         *tls.Config
+	endpoint          string
+	endpointOverrides map[string]string
+	loader            loader
+	soapVersion       SOAPVersion
+	transport         Transport
+	middleware        []Middleware
+	usernameToken     *usernameToken
+	signature         *x509Signature
+	verification      *signatureVerification
+	addressing        AddressingMode
+	baseLocation      string
+
 	wsdl            *WSDL
 	name            string
 	targetNamespace string
@@ -20,51 +36,144 @@ type Client struct {
 }
 
 // NewClient creates a new client given a WSDL specification at [url]
-func NewClient(url string) *Client {
-	client := &Client{
-		url:        url,
-		httpClient: &http.Client{},
+func NewClient(url string, options ...Option) *Client {
+	client, err := NewClientE(url, options...)
+	if err != nil {
+		panic(err)
 	}
-	client.init()
 	return client
 }
 
-func (c *Client) init() {
-	req, err := http.NewRequest("GET", c.url, nil)
+// NewClientE creates a new client given a WSDL specification at [url], returning an error instead of panicking
+func NewClientE(url string, options ...Option) (*Client, error) {
+	return NewClientContext(context.Background(), url, options...)
+}
+
+// NewClientContext creates a new client given a WSDL specification at [url], using ctx for fetching the WSDL
+func NewClientContext(ctx context.Context, url string, options ...Option) (*Client, error) {
+	client, err := newClient(url, options)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	client.loader = &httpLoader{client: client}
+	if err := client.init(ctx); err != nil {
+		return nil, err
 	}
-	res, err := c.httpClient.Do(req)
+	return client, nil
+}
+
+// NewClientFromReader creates a new client reading the WSDL specification from [reader].
+// Relative imports require WithBaseLocation
+func NewClientFromReader(reader io.Reader, options ...Option) (*Client, error) {
+	client, err := newClient("", options)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	client.url = client.baseLocation
+	if client.url == "" || isRemoteLocation(client.url) {
+		client.loader = &httpLoader{client: client}
+	} else {
+		client.loader = &fileLoader{}
 	}
-	data, err := ioutil.ReadAll(res.Body)
+	data, err := ioutil.ReadAll(reader)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	if err := client.parse(context.Background(), data); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// NewClientFromFile creates a new client reading the WSDL specification from the file at [path]
+func NewClientFromFile(path string, options ...Option) (*Client, error) {
+	client, err := newClient(path, options)
+	if err != nil {
+		return nil, err
 	}
+	client.loader = &fileLoader{}
+	if err := client.init(context.Background()); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
 
+// NewClientFromFS creates a new client reading the WSDL specification [entry] from [fsys], e.g. an embed.FS
+func NewClientFromFS(fsys fs.FS, entry string, options ...Option) (*Client, error) {
+	client, err := newClient(entry, options)
+	if err != nil {
+		return nil, err
+	}
+	client.loader = &fsLoader{fsys: fsys}
+	if err := client.init(context.Background()); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func newClient(url string, options []Option) (*Client, error) {
+	client := &Client{
+		url:               url,
+		httpClient:        &http.Client{},
+		httpHeaders:       http.Header{},
+		endpointOverrides: map[string]string{},
+	}
+	if err := client.applyOptions(options); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func (c *Client) init(ctx context.Context) error {
+	data, err := c.loader.load(ctx, c.url)
+	if err != nil {
+		return err
+	}
+	return c.parse(ctx, data)
+}
+
+func (c *Client) parse(ctx context.Context, data []byte) error {
 	// decode WSDL XML as DOM Document
-	c.wsdl = &WSDL{document: &dom.Document{}}
-	decoder := xml.NewDecoder(bytes.NewBuffer(data))
-	decoder.Strict = true
-	decoder.Decode(&c.wsdl.document)
+	document, err := decodeDocument(c.url, data)
+	if err != nil {
+		return err
+	}
+	c.wsdl = newWSDL(document)
+
+	// follow all imports and includes
+	visited := map[string]bool{c.url: true}
+	if err := c.collect(ctx, document.Root, c.url, "", visited); err != nil {
+		return err
+	}
 
 	c.name = c.wsdl.document.Root.GetAttributeValue("name")
 	c.targetNamespace = c.wsdl.document.Root.GetAttributeValue("targetNamespace")
 
 	// build services
 	c.services = map[string]*Service{}
-	list := c.wsdl.XPath("/definitions/service")
+	list := c.wsdl.allDefinitions("service")
 	for _, item := range list.All() {
 		name := item.GetAttributeValue("name")
 		c.services[name] = &Service{
-			client: c,
-			wsdl:   c.wsdl,
-			name:   name,
-			url:    item.XPath("port/address").First().GetAttributeValue("location"),
+			client:  c,
+			wsdl:    c.wsdl,
+			name:    name,
+			domNode: item,
 		}
 		c.services[name].init()
 	}
+	return nil
+}
+
+// endpointURL returns the URL to send requests for the named Service to, honoring configured overrides
+func (c *Client) endpointURL(service string, location string) string {
+	if override, exists := c.endpointOverrides[service]; exists {
+		return override
+	}
+	if c.endpoint != "" {
+		return c.endpoint
+	}
+	return location
 }
 
 // Explain outputs all available Services
@@ -78,8 +187,17 @@ func (c *Client) Explain() {
 
 // Service returns the named Service
 func (c *Client) Service(name string) *Service {
+	service, err := c.LookupService(name)
+	if err != nil {
+		panic(err)
+	}
+	return service
+}
+
+// LookupService returns the named Service, or an error wrapping ErrUnknownService
+func (c *Client) LookupService(name string) (*Service, error) {
 	if service, exists := c.services[name]; exists {
-		return service
+		return service, nil
 	}
-	panic(fmt.Errorf("Service(): unknown service with name [%s]", name))
+	return nil, fmt.Errorf("Service(): %w with name [%s]", ErrUnknownService, name)
 }
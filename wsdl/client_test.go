@@ -0,0 +1,122 @@
+package wsdl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// serveDefinitions starts a server answering every request with the given status and body
+func serveDefinitions(t *testing.T, statusCode int, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statusCode)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNewClientErrors(t *testing.T) {
+	closed := serveDefinitions(t, 200, "")
+	closed.Close()
+
+	tests := []struct {
+		name       string
+		url        string
+		statusCode int
+		body       string
+		err        error
+	}{
+		{"not found", serveDefinitions(t, 404, "missing").URL, 404, "missing", ErrHTTPStatus},
+		{"server error", serveDefinitions(t, 500, "failed").URL, 500, "failed", ErrHTTPStatus},
+		{"connection refused", closed.URL, 0, "", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, err := NewClientE(test.url)
+			var transportError *TransportError
+			if client != nil || !errors.As(err, &transportError) {
+				t.Fatalf("expected a TransportError, got [%v]", err)
+			}
+			if transportError.URL != test.url || transportError.StatusCode != test.statusCode || string(transportError.Body) != test.body {
+				t.Fatalf("unexpected %s (body %s)", transportError, transportError.Body)
+			}
+			if test.err != nil && !errors.Is(err, test.err) {
+				t.Fatalf("expected [%v], got [%v]", test.err, err)
+			}
+		})
+	}
+
+	// a document that is no WSDL is no transport error
+	if _, err := NewClientE(serveDefinitions(t, 200, "no XML").URL); !errors.Is(err, ErrInvalidWSDL) {
+		t.Fatalf("expected ErrInvalidWSDL, got [%v]", err)
+	}
+	if _, err := NewClientE(serveDefinitions(t, 200, testDefinitions(testSchema("", `<xs:element name="Request" type="xs:string"/>`))).URL); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientLookupErrors(t *testing.T) {
+	client := newTestClient(t, testSchema(`xmlns:tns="`+testNamespace+`"`, `<xs:element name="Request" type="tns:Missing"/>`),
+		WithTransport(&testTransport{}))
+
+	if _, err := client.LookupService("Missing"); !errors.Is(err, ErrUnknownService) {
+		t.Fatalf("expected ErrUnknownService, got [%v]", err)
+	}
+	if _, err := client.Service("TestService").LookupOperation("Missing"); !errors.Is(err, ErrUnknownOperation) {
+		t.Fatalf("expected ErrUnknownOperation, got [%v]", err)
+	}
+
+	// the panicking variants panic with the same errors
+	func() {
+		defer func() {
+			if err, _ := recover().(error); !errors.Is(err, ErrUnknownService) {
+				t.Fatalf("expected a panic with ErrUnknownService, got [%v]", err)
+			}
+		}()
+		client.Service("Missing")
+	}()
+
+	// schema errors surface when building the request, nothing is sent
+	if _, err := newTestRequest(t, client).SendE(); !errors.Is(err, ErrUnresolvedType) {
+		t.Fatalf("expected ErrUnresolvedType, got [%v]", err)
+	}
+	if requests := client.transport.(*testTransport).requests; len(requests) != 0 {
+		t.Fatalf("expected no request to be sent, got %d", len(requests))
+	}
+}
+
+func TestNewClientContext(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the headers are sent for /body, the body never completes
+		if r.URL.Path == "/body" {
+			w.Write([]byte("<wsdl:definitions"))
+			w.(http.Flusher).Flush()
+		}
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	var transportError *TransportError
+	for _, url := range []string{server.URL, server.URL + "/body"} {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		_, err := NewClientContext(ctx, url)
+		cancel()
+		if !errors.As(err, &transportError) || !transportError.Timeout() || !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected a timed out TransportError for [%s], got [%v]", url, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := NewClientContext(ctx, server.URL)
+	if !errors.As(err, &transportError) || transportError.Timeout() || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancelled TransportError, got [%v]", err)
+	}
+}
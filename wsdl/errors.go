@@ -0,0 +1,108 @@
+package wsdl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+var (
+	// ErrUnknownService is returned when a Service name is not defined by the WSDL
+	ErrUnknownService = errors.New("unknown service")
+	// ErrUnknownOperation is returned when an Operation name is not defined by a Service
+	ErrUnknownOperation = errors.New("unknown operation")
+	// ErrUnresolvedElement is returned when an <element> reference cannot be resolved
+	ErrUnresolvedElement = errors.New("unresolved element")
+	// ErrUnresolvedType is returned when a <complexType> or <simpleType> reference cannot be resolved
+	ErrUnresolvedType = errors.New("unresolved type")
+	// ErrUnresolvedGroup is returned when a <group> or <attributeGroup> reference cannot be resolved
+	ErrUnresolvedGroup = errors.New("unresolved group")
+	// ErrUnresolvedAttribute is returned when an <attribute> reference cannot be resolved
+	ErrUnresolvedAttribute = errors.New("unresolved attribute")
+	// ErrInvalidBody is returned when the body values do not fit the schema, e.g. several branches of a <choice> are given
+	ErrInvalidBody = errors.New("invalid body values")
+	// ErrInvalidWSDL is returned when the WSDL document cannot be decoded or its schema is inconsistent, e.g. derives from a final type
+	ErrInvalidWSDL = errors.New("invalid WSDL document")
+	// ErrNoFaultDetail is returned when decoding the detail of a Fault that carries none
+	ErrNoFaultDetail = errors.New("fault carries no detail")
+	// ErrInvalidSignature is returned when the XML signature of a response is missing or invalid
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrHTTPStatus is wrapped by a TransportError when the endpoint answered with an unexpected HTTP status
+	ErrHTTPStatus = errors.New("unexpected HTTP status")
+	// ErrMalformedResponse is returned when the response body is no well-formed SOAP envelope
+	ErrMalformedResponse = errors.New("malformed SOAP response")
+)
+
+// TransportError wraps a failed HTTP round-trip, including the status and body if a response was received
+type TransportError struct {
+	URL        string
+	StatusCode int
+	Body       []byte
+	Err        error
+}
+
+// Error implements the error interface
+func (e *TransportError) Error() string {
+	result := fmt.Sprintf("transport error for [%s]", e.URL)
+	if e.StatusCode != 0 {
+		result += fmt.Sprintf(" (HTTP %d)", e.StatusCode)
+	}
+	if e.Err != nil {
+		result += ": " + e.Err.Error()
+	}
+	return result
+}
+
+// Unwrap returns the underlying error
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout returns true if the round-trip failed because a deadline was exceeded
+func (e *TransportError) Timeout() bool {
+	if errors.Is(e.Err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(e.Err, &netErr) && netErr.Timeout()
+}
+
+// contextError prefers the context error over err, so callers can reliably check for
+// context.DeadlineExceeded and context.Canceled with errors.Is
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// ResolveError describes a schema reference (element, type, group or attribute) that could not be resolved
+type ResolveError struct {
+	Kind      error
+	FQName    string
+	Namespace string
+	Err       error
+}
+
+// Error implements the error interface
+func (e *ResolveError) Error() string {
+	result := fmt.Sprintf("%s with fqName [%s]", e.Kind, e.FQName)
+	if e.Namespace != "" {
+		result += fmt.Sprintf(" in ns [%s]", e.Namespace)
+	}
+	if e.Err != nil {
+		result += ": " + e.Err.Error()
+	}
+	return result
+}
+
+// Is reports whether target is the kind of this error (ErrUnresolvedElement, ErrUnresolvedType, ErrUnresolvedGroup or ErrUnresolvedAttribute)
+func (e *ResolveError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Unwrap returns the underlying error
+func (e *ResolveError) Unwrap() error {
+	return e.Err
+}
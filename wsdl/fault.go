@@ -1,11 +1,12 @@
 package wsdl
 
 import (
-	"fmt"
+	"encoding/xml"
+	"errors"
 	"github.com/lordkhonsu/go-soap/dom"
 )
 
-// Fault wraps a WSDL fault message
+// Fault wraps a WSDL fault message, providing the same accessors for SOAP 1.1 and SOAP 1.2 faults
 type Fault struct {
 	response *Response
 	domNode  *dom.Node
@@ -13,16 +14,155 @@ type Fault struct {
 
 // String returns a formatted Fault message
 func (f *Fault) String() string {
-	return "[wsdl/fault] " + f.domNode.XPath("faultcode").First().String() + " - " +
-		f.domNode.XPath("faultstring").First().String()
+	return "[wsdl/fault] " + f.Code() + " - " + f.Reason("")
 }
 
 // Error returns the Fault as an error object
 func (f *Fault) Error() error {
-	return fmt.Errorf(f.String())
+	return errors.New(f.String())
+}
+
+// Version returns the SOAP version of the Fault, detected by the namespace of the envelope
+func (f *Fault) Version() SOAPVersion {
+	if f.domNode.Namespace != nil && f.domNode.Namespace.Name == soap12EnvelopeNS {
+		return SOAP12
+	}
+	return SOAP11
+}
+
+// Code returns the fault code (SOAP 1.1: <faultcode>, SOAP 1.2: <Code><Value>)
+func (f *Fault) Code() string {
+	if f.Version() == SOAP12 {
+		return f.domNode.XPath("Code/Value").First().String()
+	}
+	return f.domNode.XPath("faultcode").First().String()
+}
+
+// Subcodes returns the nested subcode values, outermost first (SOAP 1.2 only, nil for SOAP 1.1)
+func (f *Fault) Subcodes() []string {
+	if f.Version() != SOAP12 {
+		return nil
+	}
+	result := []string{}
+	subcode := f.domNode.XPath("Code/Subcode").First()
+	for subcode.Exists {
+		result = append(result, subcode.XPath("Value").First().String())
+		subcode = subcode.XPath("Subcode").First()
+	}
+	return result
+}
+
+// Reason returns the human readable fault reason in the given language (SOAP 1.1: <faultstring>,
+// SOAP 1.2: <Reason><Text xml:lang>). Falls back to the first reason if [lang] is empty or not available
+func (f *Fault) Reason(lang string) string {
+	if f.Version() != SOAP12 {
+		return f.domNode.XPath("faultstring").First().String()
+	}
+	texts := f.domNode.XPath("Reason/Text")
+	if lang != "" {
+		for _, text := range texts.All() {
+			if text.GetAttributeValue("lang") == lang {
+				return text.String()
+			}
+		}
+	}
+	return texts.First().String()
+}
+
+// Role returns the role of the node that caused the fault (SOAP 1.1: <faultactor>, SOAP 1.2: <Role>)
+func (f *Fault) Role() string {
+	if f.Version() == SOAP12 {
+		return f.domNode.XPath("Role").First().String()
+	}
+	return f.domNode.XPath("faultactor").First().String()
+}
+
+// Actor is an alias of Role using the SOAP 1.1 terminology
+func (f *Fault) Actor() string {
+	return f.Role()
+}
+
+// Node returns the URI of the node that caused the fault (SOAP 1.2 only, empty for SOAP 1.1)
+func (f *Fault) Node() string {
+	if f.Version() == SOAP12 {
+		return f.domNode.XPath("Node").First().String()
+	}
+	return ""
+}
+
+// Detail returns the fault detail element (SOAP 1.1: <detail>, SOAP 1.2: <Detail>); check Exists
+func (f *Fault) Detail() *dom.Node {
+	if f.Version() == SOAP12 {
+		return f.domNode.XPath("Detail").First()
+	}
+	return f.domNode.XPath("detail").First()
 }
 
 // Details returns all embedded fault detail structures (if there are any)
 func (f *Fault) Details() *dom.NodeList {
-	return f.domNode.XPath("detail/*")
+	return f.Detail().XPath("*")
+}
+
+// Kind returns the name of the WSDL <fault> of the Operation matching the detail of this Fault
+// (empty if the Fault is no declared fault or the Response wasn't received via Request.Send)
+func (f *Fault) Kind() string {
+	name, _ := f.declaredFault()
+	return name
+}
+
+// DecodeDetail decodes the detail entry of this Fault into v, which is either a *dom.Map or
+// anything encoding/xml can unmarshal into (e.g. a pointer to a struct)
+func (f *Fault) DecodeDetail(v interface{}) error {
+	_, entry := f.declaredFault()
+	if !entry.Exists {
+		entry = f.Details().First()
+	}
+	if !entry.Exists {
+		return ErrNoFaultDetail
+	}
+
+	if target, ok := v.(*dom.Map); ok {
+		*target = entry.ToMap()
+		return nil
+	}
+
+	// inclusive canonicalization declares all namespaces in scope, those of Envelope and Body included
+	data, err := entry.Canonicalize(dom.C14N10, nil)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(data, v)
+}
+
+// declaredFault finds the <fault> declared by the Operation whose message element matches a detail entry
+func (f *Fault) declaredFault() (string, *dom.Node) {
+	if f.response == nil || f.response.operation == nil {
+		return "", &dom.Node{}
+	}
+	operation := f.response.operation
+	details := f.Details()
+
+	for _, fault := range operation.portTypeNode.XPath("fault").All() {
+		_, messageName := dom.SplitFQName(fault.GetAttributeValue("message"))
+		message := operation.wsdl.findDefinition("message", messageName)
+		part := message.XPath("part").First()
+		if !part.Exists {
+			continue
+		}
+		element, err := operation.wsdl.LookupElement(part.GetAttributeValue("element"), part)
+		if err != nil {
+			continue
+		}
+		// the QName must match, entries of another namespace are no declared fault
+		for _, entry := range details.All() {
+			namespace := ""
+			if entry.Namespace != nil {
+				namespace = entry.Namespace.Name
+			}
+			if entry.Name == element.Name() && namespace == element.Namespace() {
+				return fault.GetAttributeValue("name"), entry
+			}
+		}
+	}
+	return "", &dom.Node{}
 }
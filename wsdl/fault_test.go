@@ -0,0 +1,194 @@
+package wsdl
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lordkhonsu/go-soap/dom"
+)
+
+const testFaultResponse = `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" xmlns:t="urn:t">
+  <s:Body>
+    <s:Fault>
+      <faultcode>s:Client</faultcode>
+      <faultstring>bad request</faultstring>
+      <detail>
+        <t:BadFault><t:Code>42</t:Code><t:Field>name</t:Field></t:BadFault>
+      </detail>
+    </s:Fault>
+  </s:Body>
+</s:Envelope>`
+
+func TestFaultDecodeDetailNamespaced(t *testing.T) {
+	response, err := ParseResponseE([]byte(testFaultResponse))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fault := response.Fault()
+	if fault == nil {
+		t.Fatal("expected a fault")
+	}
+
+	var detail struct {
+		XMLName xml.Name `xml:"urn:t BadFault"`
+		Code    int      `xml:"urn:t Code"`
+		Field   string   `xml:"urn:t Field"`
+	}
+	if err := fault.DecodeDetail(&detail); err != nil {
+		t.Fatal(err)
+	}
+	if detail.Code != 42 || detail.Field != "name" {
+		t.Fatalf("unexpected detail %+v", detail)
+	}
+
+	var mapping dom.Map
+	if err := fault.DecodeDetail(&mapping); err != nil {
+		t.Fatal(err)
+	}
+	if mapping["Code"] != "42" {
+		t.Fatalf("unexpected detail %v", mapping)
+	}
+}
+
+const testFault11 = `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><s:Fault>
+  <faultcode>s:Server</faultcode>
+  <faultstring xml:lang="en">disk 100% full</faultstring>
+  <faultactor>http://example.com/actor</faultactor>
+  <detail><Quota>5</Quota></detail>
+</s:Fault></s:Body></s:Envelope>`
+
+const testFault12 = `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope" xmlns:m="urn:m"><env:Body><env:Fault>
+  <env:Code><env:Value>env:Sender</env:Value>
+    <env:Subcode><env:Value>m:MessageTimeout</env:Value><env:Subcode><env:Value>m:Late</env:Value></env:Subcode></env:Subcode>
+  </env:Code>
+  <env:Reason><env:Text xml:lang="en">timeout at 100%</env:Text><env:Text xml:lang="de">Zeitüberschreitung</env:Text></env:Reason>
+  <env:Node>http://example.com/node</env:Node>
+  <env:Role>http://example.com/role</env:Role>
+  <env:Detail><m:MaxTime>P5M</m:MaxTime></env:Detail>
+</env:Fault></env:Body></env:Envelope>`
+
+func TestFaultAccessors(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		version  SOAPVersion
+		code     string
+		subcodes []string
+		reasons  map[string]string
+		role     string
+		node     string
+		detail   string
+	}{
+		{"SOAP 1.1", testFault11, SOAP11, "s:Server", nil,
+			map[string]string{"": "disk 100% full", "de": "disk 100% full"},
+			"http://example.com/actor", "", "Quota"},
+		{"SOAP 1.2", testFault12, SOAP12, "env:Sender", []string{"m:MessageTimeout", "m:Late"},
+			// unknown languages fall back to the first reason
+			map[string]string{"": "timeout at 100%", "en": "timeout at 100%", "de": "Zeitüberschreitung", "fr": "timeout at 100%"},
+			"http://example.com/role", "http://example.com/node", "MaxTime"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := ParseResponseE([]byte(test.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			fault := response.Fault()
+			if fault == nil {
+				t.Fatal("expected a fault")
+			}
+
+			if fault.Version() != test.version {
+				t.Fatalf("expected version %v, got %v", test.version, fault.Version())
+			}
+			if fault.Code() != test.code {
+				t.Fatalf("expected code [%s], got [%s]", test.code, fault.Code())
+			}
+			if !reflect.DeepEqual(fault.Subcodes(), test.subcodes) {
+				t.Fatalf("expected subcodes %v, got %v", test.subcodes, fault.Subcodes())
+			}
+			for lang, expected := range test.reasons {
+				if reason := fault.Reason(lang); reason != expected {
+					t.Fatalf("expected reason [%s] for [%s], got [%s]", expected, lang, reason)
+				}
+			}
+			if fault.Role() != test.role || fault.Actor() != test.role {
+				t.Fatalf("expected role [%s], got [%s]", test.role, fault.Role())
+			}
+			if fault.Node() != test.node {
+				t.Fatalf("expected node [%s], got [%s]", test.node, fault.Node())
+			}
+			if name := fault.Details().First().Name; name != test.detail {
+				t.Fatalf("expected detail [%s], got [%s]", test.detail, name)
+			}
+
+			// reasons are no format strings
+			expected := "[wsdl/fault] " + test.code + " - " + test.reasons[""]
+			if fault.String() != expected || fault.Error().Error() != expected {
+				t.Fatalf("expected [%s], got [%s]", expected, fault.Error())
+			}
+		})
+	}
+}
+
+// testFaultDefinitions declares the fault CallError (element tns:CallError) for the operation of the test WSDL
+func testFaultDefinitions() string {
+	return strings.NewReplacer(
+		`<wsdl:portType `, `<wsdl:message name="CallFault"><wsdl:part name="detail" element="tns:CallError"/></wsdl:message>
+  <wsdl:portType `,
+		`<wsdl:output message="tns:CallOut"/>`, `<wsdl:output message="tns:CallOut"/>
+      <wsdl:fault name="CallError" message="tns:CallFault"/>`,
+	).Replace(testDefinitions(testSchema(`elementFormDefault="qualified"`,
+		`<xs:element name="Request" type="xs:string"/>
+    <xs:element name="CallError"><xs:complexType><xs:sequence>
+      <xs:element name="Reason" type="xs:string"/>
+    </xs:sequence></xs:complexType></xs:element>`)))
+}
+
+func TestFaultKind(t *testing.T) {
+	tests := []struct {
+		name   string
+		detail string
+		kind   string
+	}{
+		{"declared", `<t:CallError xmlns:t="` + testNamespace + `"><t:Reason>declared</t:Reason></t:CallError>`, "CallError"},
+		{"declared after other entries", `<o:Info xmlns:o="urn:other"/><CallError xmlns="` + testNamespace + `"><Reason>declared</Reason></CallError>`,
+			"CallError"},
+		{"wrong namespace", `<o:CallError xmlns:o="urn:other"><o:Reason>other</o:Reason></o:CallError>`, ""},
+		{"unqualified", `<CallError><Reason>unqualified</Reason></CallError>`, ""},
+		{"undeclared", `<t:Other xmlns:t="` + testNamespace + `"><t:Reason>other</t:Reason></t:Other>`, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, err := NewClientFromReader(strings.NewReader(testFaultDefinitions()), WithTransport(respondWith(500, "text/xml",
+				`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><s:Fault>`+
+					`<faultcode>s:Client</faultcode><faultstring>failed</faultstring><detail>`+test.detail+`</detail>`+
+					`</s:Fault></s:Body></s:Envelope>`)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			response, err := newTestRequest(t, client).SendE()
+			if err != nil {
+				t.Fatal(err)
+			}
+			fault := response.Fault()
+			if fault == nil {
+				t.Fatal("expected a fault")
+			}
+			if kind := fault.Kind(); kind != test.kind {
+				t.Fatalf("expected kind [%s], got [%s]", test.kind, kind)
+			}
+
+			// the declared entry is decoded, the first one otherwise
+			var detail dom.Map
+			if err := fault.DecodeDetail(&detail); err != nil {
+				t.Fatal(err)
+			}
+			if test.kind != "" && detail["Reason"] != "declared" {
+				t.Fatalf("expected the declared detail, got %v", detail)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package wsdl
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/lordkhonsu/go-soap/dom"
+)
+
+// loader fetches the raw data of a WSDL document from its location
+type loader interface {
+	load(ctx context.Context, location string) ([]byte, error)
+	// resolve returns the location of [reference] relative to the document at [base]
+	resolve(base string, reference string) string
+}
+
+// httpLoader fetches documents with a HTTP GET using the Client's HTTP settings
+type httpLoader struct {
+	client *Client
+}
+
+func (l *httpLoader) load(ctx context.Context, location string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", location, nil)
+	if err != nil {
+		return nil, &TransportError{URL: location, Err: err}
+	}
+	l.client.applyHeaders(req.Header)
+
+	res, err := l.client.httpClient.Do(req)
+	if err != nil {
+		return nil, &TransportError{URL: location, Err: contextError(ctx, err)}
+	}
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, &TransportError{URL: location, StatusCode: res.StatusCode, Err: contextError(ctx, err)}
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, &TransportError{URL: location, StatusCode: res.StatusCode, Body: data, Err: ErrHTTPStatus}
+	}
+	return data, nil
+}
+
+func (l *httpLoader) resolve(base string, reference string) string {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return reference
+	}
+	referenceURL, err := url.Parse(reference)
+	if err != nil {
+		return reference
+	}
+	return baseURL.ResolveReference(referenceURL).String()
+}
+
+// fileLoader reads documents from the local filesystem
+type fileLoader struct{}
+
+func (l *fileLoader) load(ctx context.Context, location string) ([]byte, error) {
+	return os.ReadFile(location)
+}
+
+func (l *fileLoader) resolve(base string, reference string) string {
+	if filepath.IsAbs(reference) {
+		return reference
+	}
+	return filepath.Join(filepath.Dir(base), filepath.FromSlash(reference))
+}
+
+// fsLoader reads documents from a fs.FS, e.g. an embed.FS
+type fsLoader struct {
+	fsys fs.FS
+}
+
+func (l *fsLoader) load(ctx context.Context, location string) ([]byte, error) {
+	return fs.ReadFile(l.fsys, location)
+}
+
+func (l *fsLoader) resolve(base string, reference string) string {
+	return path.Join(path.Dir(base), reference)
+}
+
+func isRemoteLocation(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// resolveLocation resolves a wsdl:import / xsd:import / xsd:include reference against the importing document
+func (c *Client) resolveLocation(base string, reference string) string {
+	if isRemoteLocation(reference) {
+		return reference
+	}
+	if isRemoteLocation(base) {
+		return (&httpLoader{client: c}).resolve(base, reference)
+	}
+	return c.loader.resolve(base, reference)
+}
+
+// loadLocation loads the document at location, always using HTTP for remote locations
+func (c *Client) loadLocation(ctx context.Context, location string) ([]byte, error) {
+	if isRemoteLocation(location) {
+		return (&httpLoader{client: c}).load(ctx, location)
+	}
+	return c.loader.load(ctx, location)
+}
+
+func decodeDocument(location string, data []byte) (*dom.Document, error) {
+	document := &dom.Document{}
+	decoder := xml.NewDecoder(bytes.NewBuffer(data))
+	decoder.Strict = true
+	if err := decoder.Decode(document); err != nil {
+		return nil, fmt.Errorf("NewClient(): %w [%s]: %v", ErrInvalidWSDL, location, err)
+	}
+	return document, nil
+}
+
+// collect registers all <definitions> and <schema> nodes reachable from root, following imports and includes
+func (c *Client) collect(ctx context.Context, root *dom.Node, location string, namespace string, visited map[string]bool) error {
+	switch root.Name {
+	case "definitions":
+		c.wsdl.definitions = append(c.wsdl.definitions, root)
+		for _, schema := range root.XPath("types/schema").All() {
+			if err := c.collect(ctx, schema, location, "", visited); err != nil {
+				return err
+			}
+		}
+		for _, wsdlImport := range root.XPath("import").All() {
+			if err := c.collectReference(ctx, wsdlImport.GetAttributeValue("location"), location, "", visited); err != nil {
+				return err
+			}
+		}
+
+	case "schema":
+		// an included schema without targetNamespace takes over the namespace of the including schema
+		if targetNamespace := root.GetAttributeValue("targetNamespace"); targetNamespace != "" {
+			namespace = targetNamespace
+		}
+		c.wsdl.schemas[namespace] = append(c.wsdl.schemas[namespace], root)
+		for _, schemaImport := range root.XPath("import").All() {
+			if err := c.collectReference(ctx, schemaImport.GetAttributeValue("schemaLocation"), location, "", visited); err != nil {
+				return err
+			}
+		}
+		for _, schemaInclude := range root.XPath("include").All() {
+			if err := c.collectReference(ctx, schemaInclude.GetAttributeValue("schemaLocation"), location, namespace, visited); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Client) collectReference(ctx context.Context, reference string, base string, namespace string, visited map[string]bool) error {
+	// imports without location only declare a namespace dependency
+	if reference == "" {
+		return nil
+	}
+
+	// relative references need to know where the importing document came from
+	if base == "" && !isRemoteLocation(reference) {
+		return fmt.Errorf("NewClient(): %w: relative reference [%s] without base location, see WithBaseLocation",
+			ErrInvalidWSDL, reference)
+	}
+
+	// guard against import cycles
+	location := c.resolveLocation(base, reference)
+	if visited[location] {
+		return nil
+	}
+	visited[location] = true
+
+	data, err := c.loadLocation(ctx, location)
+	if err != nil {
+		return err
+	}
+	document, err := decodeDocument(location, data)
+	if err != nil {
+		return err
+	}
+	return c.collect(ctx, document.Root, location, namespace, visited)
+}
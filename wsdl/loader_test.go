@@ -0,0 +1,119 @@
+package wsdl
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestNewClientFromReaderImports(t *testing.T) {
+	definitions := testDefinitions(`
+  <xs:schema targetNamespace="` + testNamespace + `">
+    <xs:include schemaLocation="types.xsd"/>
+  </xs:schema>`)
+	types := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="Request" type="xs:string"/>
+  <xs:element name="Response" type="xs:string"/>
+</xs:schema>`
+
+	// relative imports cannot be resolved without a base location
+	if _, err := NewClientFromReader(strings.NewReader(definitions)); !errors.Is(err, ErrInvalidWSDL) {
+		t.Fatalf("expected ErrInvalidWSDL, got %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "types.xsd"), []byte(types), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClientFromReader(strings.NewReader(definitions), WithBaseLocation(filepath.Join(dir, "test.wsdl")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.wsdl.LookupElement("tns:Request", nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testImportFS holds a WSDL whose request type is spread over imported and included schemas, which import each other
+var testImportFS = fstest.MapFS{
+	"test.wsdl": {Data: []byte(testDefinitions(testSchema(`xmlns:o="urn:other"`,
+		`<xs:import namespace="urn:other" schemaLocation="schemas/other.xsd"/>
+    <xs:element name="Request" type="o:Item"/>`)))},
+	"schemas/other.xsd": {Data: []byte(`<xs:schema targetNamespace="urn:other" xmlns:o="urn:other" xmlns:c="urn:cycle"
+  xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:include schemaLocation="common.xsd"/>
+  <xs:import namespace="urn:cycle" schemaLocation="cycle.xsd"/>
+  <xs:complexType name="Item"><xs:sequence>
+    <xs:element name="Code" type="o:Code"/>
+    <xs:element name="Ref" type="c:Ref"/>
+  </xs:sequence></xs:complexType>
+</xs:schema>`)},
+	"schemas/common.xsd": {Data: []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:simpleType name="Code"><xs:restriction base="xs:string"/></xs:simpleType>
+</xs:schema>`)},
+	"schemas/cycle.xsd": {Data: []byte(`<xs:schema targetNamespace="urn:cycle" xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:import namespace="urn:other" schemaLocation="other.xsd"/>
+  <xs:simpleType name="Ref"><xs:restriction base="xs:string"/></xs:simpleType>
+</xs:schema>`)},
+	"broken.wsdl": {Data: []byte(testDefinitions(testSchema("",
+		`<xs:import namespace="urn:other" schemaLocation="schemas/missing.xsd"/>
+    <xs:element name="Request" type="xs:string"/>`)))},
+}
+
+func TestNewClientImports(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.FS(testImportFS)))
+	defer server.Close()
+
+	clients := map[string]func(entry string) (*Client, error){
+		"fs": func(entry string) (*Client, error) {
+			return NewClientFromFS(testImportFS, entry)
+		},
+		"http": func(entry string) (*Client, error) {
+			return NewClientE(server.URL + "/" + entry)
+		},
+	}
+	for name, newClient := range clients {
+		t.Run(name, func(t *testing.T) {
+			client, err := newClient("test.wsdl")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, namespace := range []string{testNamespace, "urn:other", "urn:cycle"} {
+				if len(client.wsdl.schemas[namespace]) == 0 {
+					t.Fatalf("expected the schemas of [%s]", namespace)
+				}
+			}
+			// the included schema without targetNamespace is loaded once, into the namespace of the including one
+			if count := len(client.wsdl.schemas["urn:other"]); count != 2 {
+				t.Fatalf("expected 2 schemas of [urn:other], got %d", count)
+			}
+
+			envelope, err := buildTestBody(t, client, orderedValues("Code", "c", "Ref", "r"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result := describeRequest(envelope); result != "Code=c Ref=r" {
+				t.Fatalf("unexpected request [%s]", result)
+			}
+
+			if _, err := newClient("broken.wsdl"); err == nil {
+				t.Fatal("expected the missing import to fail")
+			}
+		})
+	}
+
+	if _, err := NewClientFromFS(testImportFS, "broken.wsdl"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected fs.ErrNotExist, got [%v]", err)
+	}
+	var transportError *TransportError
+	if _, err := NewClientE(server.URL + "/broken.wsdl"); !errors.As(err, &transportError) ||
+		transportError.StatusCode != 404 || !strings.HasSuffix(transportError.URL, "/schemas/missing.xsd") {
+		t.Fatalf("expected a TransportError for the missing import, got [%v]", err)
+	}
+}
@@ -0,0 +1,57 @@
+package wsdl
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/lordkhonsu/go-soap/dom"
+)
+
+// Call describes a single outgoing SOAP call passing through the middleware chain.
+// Middleware may modify the Envelope, URL, Action and Header before handing the Call on;
+// the X.509 signature is added to the Envelope after all middleware ran, so it covers their changes
+type Call struct {
+	Request  *Request
+	Envelope *dom.Document
+	URL      string
+	Action   string
+	Header   http.Header
+}
+
+// Handler performs a Call and returns the received Response
+type Handler func(ctx context.Context, call *Call) (*Response, error)
+
+// Middleware wraps a Handler; it may modify the Call, short-circuit it by not calling next,
+// or inspect and translate the Response and error returned by next
+type Middleware func(next Handler) Handler
+
+// WithMiddleware registers middleware for all requests of the Client, the first one being the outermost
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(c *Client) {
+		c.middleware = append(c.middleware, middleware...)
+	}
+}
+
+// Use registers middleware for all requests created afterwards, the first one being the outermost
+func (c *Client) Use(middleware ...Middleware) {
+	c.middleware = append(c.middleware, middleware...)
+}
+
+// Use appends middleware to the chain of this Request (initialized with the Client's middleware)
+func (r *Request) Use(middleware ...Middleware) {
+	r.middleware = append(r.middleware, middleware...)
+}
+
+// SetMiddleware replaces the middleware chain of this Request, dropping the Client's middleware
+func (r *Request) SetMiddleware(middleware ...Middleware) {
+	r.middleware = append([]Middleware{}, middleware...)
+}
+
+// chain wraps the final Handler with all middleware of the Request
+func (r *Request) chain(final Handler) Handler {
+	handler := final
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		handler = r.middleware[i](handler)
+	}
+	return handler
+}
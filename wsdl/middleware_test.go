@@ -0,0 +1,148 @@
+package wsdl
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lordkhonsu/go-soap/dom"
+)
+
+const testResponseEnvelope = `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>` +
+	`<t:Response xmlns:t="` + testNamespace + `">ok</t:Response></s:Body></s:Envelope>`
+
+// recordingMiddleware appends name to trace before and after calling next
+func recordingMiddleware(trace *[]string, name string) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (*Response, error) {
+			*trace = append(*trace, ">"+name)
+			response, err := next(ctx, call)
+			*trace = append(*trace, "<"+name)
+			return response, err
+		}
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	trace := []string{}
+	transport := respondWith(200, "text/xml", testResponseEnvelope)
+	client := newTestClient(t, testSchema("", `<xs:element name="Request" type="xs:string"/>`),
+		WithTransport(transport), WithMiddleware(recordingMiddleware(&trace, "client1"), recordingMiddleware(&trace, "client2")))
+	client.Use(recordingMiddleware(&trace, "client3"))
+
+	request := newTestRequest(t, client)
+	request.Use(recordingMiddleware(&trace, "request"))
+	if _, err := request.SendE(); err != nil {
+		t.Fatal(err)
+	}
+	if result := strings.Join(trace, " "); result != ">client1 >client2 >client3 >request <request <client3 <client2 <client1" {
+		t.Fatalf("unexpected order [%s]", result)
+	}
+
+	// SetMiddleware drops the middleware of the client
+	trace = []string{}
+	request = newTestRequest(t, client)
+	request.SetMiddleware(recordingMiddleware(&trace, "only"))
+	if _, err := request.SendE(); err != nil {
+		t.Fatal(err)
+	}
+	if result := strings.Join(trace, " "); result != ">only <only" {
+		t.Fatalf("unexpected order [%s]", result)
+	}
+}
+
+func TestMiddlewareChanges(t *testing.T) {
+	transport := respondWith(200, "text/xml", testResponseEnvelope)
+	client := newTestClient(t, testSchema("", `<xs:element name="Request" type="xs:string"/>`), WithTransport(transport))
+	request := newTestRequest(t, client)
+	request.Use(func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (*Response, error) {
+			call.URL = "http://localhost/changed"
+			call.Header.Set("Authorization", "Bearer token")
+			call.Envelope.Root.XPath("Header").First().NewChildren("Trace", "urn:trace").SetValue("1")
+
+			response, err := next(ctx, call)
+			if err != nil {
+				return nil, err
+			}
+			response.Body().XPath("Response").First().SetValue("changed")
+			return response, nil
+		}
+	})
+
+	response, err := request.SendE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := transport.requests[0]
+	if sent.URL != "http://localhost/changed" || sent.Header.Get("Authorization") != "Bearer token" {
+		t.Fatalf("expected the changed call, got [%s] %v", sent.URL, sent.Header)
+	}
+	envelope, err := dom.Parse(sent.Envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if envelope.Root.XPath("Header/Trace").First().String() != "1" {
+		t.Fatalf("expected the changed envelope, got %s", sent.Envelope)
+	}
+	if value := response.Body().XPath("Response").First().String(); value != "changed" {
+		t.Fatalf("expected the changed response, got [%s]", value)
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	transport := &testTransport{}
+	client := newTestClient(t, testSchema("", `<xs:element name="Request" type="xs:string"/>`), WithTransport(transport))
+	cached, err := ParseResponseE([]byte(testResponseEnvelope))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := newTestRequest(t, client)
+	request.Use(func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (*Response, error) {
+			return cached, nil
+		}
+	})
+	response, err := request.SendE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response != cached || len(transport.requests) != 0 {
+		t.Fatal("expected the cached response without calling the transport")
+	}
+}
+
+func TestMiddlewareErrors(t *testing.T) {
+	failure := errors.New("connection refused")
+	transport := &testTransport{respond: func(ctx context.Context, request *TransportRequest) (*TransportResponse, error) {
+		return nil, failure
+	}}
+	errTranslated := errors.New("backend unavailable")
+	client := newTestClient(t, testSchema("", `<xs:element name="Request" type="xs:string"/>`), WithTransport(transport))
+
+	// errors of the transport reach the middleware wrapped in a TransportError
+	var seen error
+	request := newTestRequest(t, client)
+	request.Use(func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (*Response, error) {
+			_, seen = next(ctx, call)
+			return nil, errTranslated
+		}
+	})
+	if _, err := request.SendE(); err != errTranslated {
+		t.Fatalf("expected the translated error, got [%v]", err)
+	}
+	var transportError *TransportError
+	if !errors.As(seen, &transportError) || !errors.Is(seen, failure) {
+		t.Fatalf("expected a TransportError wrapping the failure, got [%v]", seen)
+	}
+
+	// without middleware translating it, the error is returned as is
+	request = newTestRequest(t, client)
+	request.Use(recordingMiddleware(&[]string{}, "passing"))
+	if _, err := request.SendE(); !errors.Is(err, failure) {
+		t.Fatalf("expected the failure, got [%v]", err)
+	}
+}
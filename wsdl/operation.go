@@ -11,6 +11,29 @@ type Operation struct {
 	service *Service
 	name    string
 	domNode *dom.Node
+
+	url         string
+	soapVersion SOAPVersion
+	addressing  bool
+
+	// the matching <operation> of the <portType>, declaring the messages
+	portTypeNode *dom.Node
+}
+
+// SOAPVersion returns the SOAP version used for this Operation
+func (o *Operation) SOAPVersion() SOAPVersion {
+	return o.soapVersion
+}
+
+// inputMessage returns the <message> used as input for this Operation
+func (o *Operation) inputMessage() *dom.Node {
+	// the binding may carry the message itself (non-standard, but common), otherwise ask the portType
+	reference := o.domNode.XPath("input").First().GetAttributeValue("message")
+	if reference == "" {
+		reference = o.portTypeNode.XPath("input").First().GetAttributeValue("message")
+	}
+	_, messageName := dom.SplitFQName(reference)
+	return o.wsdl.findDefinition("message", messageName)
 }
 
 // NewRequest creates a new Request instance for this Operation
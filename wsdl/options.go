@@ -0,0 +1,125 @@
+package wsdl
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+)
+
+// Option configures optional Client behaviour, see the With* functions
+type Option func(c *Client)
+
+// WithHTTPClient uses the given http.Client for fetching the WSDL and sending requests
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithEndpoint sends requests for all Services to [url] instead of the location advertised by the WSDL
+func WithEndpoint(url string) Option {
+	return func(c *Client) {
+		c.endpoint = url
+	}
+}
+
+// WithEndpointOverride sends requests for the named Service to [url] instead of the location advertised by the WSDL
+func WithEndpointOverride(service string, url string) Option {
+	return func(c *Client) {
+		c.endpointOverrides[service] = url
+	}
+}
+
+// WithDefaultHTTPHeaders adds the given headers to every HTTP request made by the Client
+func WithDefaultHTTPHeaders(headers http.Header) Option {
+	return func(c *Client) {
+		for name, values := range headers {
+			for _, value := range values {
+				c.httpHeaders.Add(name, value)
+			}
+		}
+	}
+}
+
+// WithTransport sends all requests via the given Transport instead of HTTP (the WSDL is still loaded via HTTP)
+func WithTransport(transport Transport) Option {
+	return func(c *Client) {
+		c.transport = transport
+	}
+}
+
+// WithSOAPVersion forces the given SOAP version, picking the matching ports if the WSDL exposes both versions
+func WithSOAPVersion(version SOAPVersion) Option {
+	return func(c *Client) {
+		c.soapVersion = version
+	}
+}
+
+// WithTLSConfig uses the given TLS configuration (client certificates, root CAs, ...) for all HTTP requests
+func WithTLSConfig(config *tls.Config) Option {
+	return func(c *Client) {
+		c.tlsConfig = config
+	}
+}
+
+// WithBaseLocation resolves relative imports of a WSDL read by NewClientFromReader against [location],
+// the URL or file path the WSDL was read from
+func WithBaseLocation(location string) Option {
+	return func(c *Client) {
+		c.baseLocation = location
+	}
+}
+
+// WithUserAgent sets the User-Agent header for every HTTP request made by the Client
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+func (c *Client) applyOptions(options []Option) error {
+	for _, option := range options {
+		option(c)
+	}
+
+	if c.tlsConfig != nil {
+		if err := c.applyTLSConfig(); err != nil {
+			return err
+		}
+	}
+
+	if c.transport == nil {
+		c.transport = &HTTPTransport{Client: c.httpClient}
+	}
+	return nil
+}
+
+func (c *Client) applyTLSConfig() error {
+	// never modify the http.Client or http.Transport handed in by the caller
+	base := c.httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	transport, ok := base.(*http.Transport)
+	if !ok {
+		return fmt.Errorf("WithTLSConfig(): transport of type [%T] does not support a TLS configuration", base)
+	}
+	transport = transport.Clone()
+	transport.TLSClientConfig = c.tlsConfig
+
+	httpClient := *c.httpClient
+	httpClient.Transport = transport
+	c.httpClient = &httpClient
+	return nil
+}
+
+func (c *Client) applyHeaders(header http.Header) {
+	for name, values := range c.httpHeaders {
+		for _, value := range values {
+			header.Add(name, value)
+		}
+	}
+	if c.userAgent != "" {
+		header.Set("User-Agent", c.userAgent)
+	}
+}
@@ -0,0 +1,173 @@
+package wsdl
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClientOptionsRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		url     string
+		header  http.Header
+	}{
+		{"defaults", nil, "http://localhost/test", http.Header{}},
+		{"endpoint", []Option{WithEndpoint("http://localhost/all")}, "http://localhost/all", http.Header{}},
+		{"endpoint override", []Option{WithEndpoint("http://localhost/all"), WithEndpointOverride("TestService", "http://localhost/service")},
+			"http://localhost/service", http.Header{}},
+		{"endpoint override of another service", []Option{WithEndpointOverride("OtherService", "http://localhost/service")},
+			"http://localhost/test", http.Header{}},
+		{"default headers", []Option{WithDefaultHTTPHeaders(http.Header{"X-Tenant": {"a", "b"}}),
+			WithDefaultHTTPHeaders(http.Header{"X-Tenant": {"c"}, "Authorization": {"Basic dTpw"}})},
+			"http://localhost/test", http.Header{"X-Tenant": {"a", "b", "c"}, "Authorization": {"Basic dTpw"}}},
+		{"user agent", []Option{WithDefaultHTTPHeaders(http.Header{"User-Agent": {"default"}}), WithUserAgent("test/1.0")},
+			"http://localhost/test", http.Header{"User-Agent": {"test/1.0"}}},
+		{"content type not overridden", []Option{WithDefaultHTTPHeaders(http.Header{"Content-Type": {"text/plain"}})},
+			"http://localhost/test", http.Header{"Content-Type": {"text/xml; charset=utf-8"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transport := &testTransport{}
+			client := newTestClient(t, testSchema("", `<xs:element name="Request" type="xs:string"/>`),
+				append(test.options, WithTransport(transport))...)
+			if _, err := newTestRequest(t, client).SendE(); err != nil {
+				t.Fatal(err)
+			}
+
+			sent := transport.requests[0]
+			if sent.URL != test.url {
+				t.Fatalf("expected URL [%s], got [%s]", test.url, sent.URL)
+			}
+			for name, values := range test.header {
+				if result := strings.Join(sent.Header.Values(name), ","); result != strings.Join(values, ",") {
+					t.Fatalf("expected %s [%s], got [%s]", name, strings.Join(values, ","), result)
+				}
+			}
+		})
+	}
+}
+
+// roundTripperFunc is a http.RoundTripper calling itself
+type roundTripperFunc func(request *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+// serveService starts a server returning the test definitions for GET and a response envelope for POST,
+// recording the requests received
+func serveService(t *testing.T, server *httptest.Server, received *[]*http.Request) {
+	t.Helper()
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*received = append(*received, r)
+		if r.Method == http.MethodGet {
+			w.Write([]byte(testDefinitions(testSchema("", `<xs:element name="Request" type="xs:string"/>`))))
+			return
+		}
+		ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(testResponseEnvelope))
+	})
+	t.Cleanup(server.Close)
+}
+
+func TestClientOptionsHTTP(t *testing.T) {
+	received := []*http.Request{}
+	server := httptest.NewUnstartedServer(nil)
+	serveService(t, server, &received)
+	server.Start()
+
+	sent := 0
+	httpClient := &http.Client{Transport: roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		sent++
+		return http.DefaultTransport.RoundTrip(request)
+	})}
+
+	// the http.Client is used for loading the WSDL and sending requests, along with the default headers
+	client, err := NewClientE(server.URL, WithHTTPClient(httpClient), WithEndpoint(server.URL+"/service"),
+		WithDefaultHTTPHeaders(http.Header{"X-Tenant": {"a"}}), WithUserAgent("test/1.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newTestRequest(t, client).SendE(); err != nil {
+		t.Fatal(err)
+	}
+	if sent != 2 || len(received) != 2 {
+		t.Fatalf("expected 2 requests via the http.Client, got %d (%d received)", sent, len(received))
+	}
+	for _, request := range received {
+		if request.Header.Get("X-Tenant") != "a" || request.UserAgent() != "test/1.0" {
+			t.Fatalf("expected the default headers, got %v", request.Header)
+		}
+	}
+	if post := received[1]; post.URL.Path != "/service" || post.Header.Get("SOAPAction") != testNamespace+"/Call" {
+		t.Fatalf("unexpected request to [%s] with %v", post.URL, post.Header)
+	}
+
+	// a Transport takes precedence, the http.Client only loads the WSDL
+	sent = 0
+	transport := &testTransport{}
+	client, err = NewClientE(server.URL, WithHTTPClient(httpClient), WithTransport(transport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newTestRequest(t, client).SendE(); err != nil {
+		t.Fatal(err)
+	}
+	if sent != 1 || len(transport.requests) != 1 {
+		t.Fatalf("expected the WSDL via the http.Client and the request via the Transport, got %d and %d", sent, len(transport.requests))
+	}
+}
+
+func TestClientOptionsTLS(t *testing.T) {
+	received := []*http.Request{}
+	server := httptest.NewUnstartedServer(nil)
+	serveService(t, server, &received)
+	server.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	server.StartTLS()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	original := &http.Transport{}
+	httpClient := &http.Client{Transport: original}
+
+	// the server's certificate is unknown to the http.Client handed in
+	if _, err := NewClientE(server.URL, WithHTTPClient(httpClient)); err == nil {
+		t.Fatal("expected an unknown certificate authority")
+	}
+
+	client, err := NewClientE(server.URL, WithHTTPClient(httpClient), WithTLSConfig(&tls.Config{RootCAs: roots}),
+		WithEndpoint(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newTestRequest(t, client).SendE(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the http.Client and its Transport are cloned instead of modified (net/http itself may set up a TLSClientConfig for HTTP/2)
+	if httpClient.Transport != original || original.TLSClientConfig != nil && original.TLSClientConfig.RootCAs != nil {
+		t.Fatal("expected the http.Client handed in to be unchanged")
+	}
+	if client.httpClient == httpClient || client.httpClient.Transport == original {
+		t.Fatal("expected a clone of the http.Client")
+	}
+	if _, err := NewClientE(server.URL, WithHTTPClient(httpClient)); err == nil {
+		t.Fatal("expected an unknown certificate authority after configuring another client")
+	}
+
+	// only a http.Transport can carry the configuration
+	custom := &http.Client{Transport: roundTripperFunc(http.DefaultTransport.RoundTrip)}
+	if _, err := NewClientE(server.URL, WithHTTPClient(custom), WithTLSConfig(&tls.Config{RootCAs: roots})); err == nil ||
+		errors.As(err, new(*TransportError)) {
+		t.Fatalf("expected a configuration error, got [%v]", err)
+	}
+}
@@ -1,8 +1,9 @@
 package wsdl
 
 import (
-	"bytes"
-	"io/ioutil"
+	"context"
+	"encoding/xml"
+	"errors"
 	"net/http"
 
 	"github.com/lordkhonsu/go-soap/dom"
@@ -24,27 +25,38 @@ type Request struct {
 	bodyValues   *dom.Document
 
 	typeExtensions map[string]string
+	middleware     []Middleware
+	usernameToken  *usernameToken
+	signature      *x509Signature
+
+	addressing bool
+	messageID  string
+	replyTo    string
 }
 
 func (r *Request) init() {
 	// build header+body values
 	r.typeExtensions = map[string]string{}
+	r.middleware = append([]Middleware{}, r.client.middleware...)
+	r.usernameToken = r.client.usernameToken
+	r.signature = r.client.signature
+	r.addressing = r.operation.addressing
 	r.headerValues = dom.NewDocument("Header")
 	r.bodyValues = dom.NewDocument("Body")
 
 	// build envelope
 	r.envelope = dom.NewDocument("s:Envelope")
 	r.rootNode = r.envelope.Root
-	r.rootNode.RegisterNS("http://www.w3.org/2001/XMLSchema-instance", "i")
-	r.rootNode.RegisterNS("http://schemas.xmlsoap.org/soap/envelope/", "s")
+	envelopeNS := r.operation.soapVersion.EnvelopeNamespace()
+	r.rootNode.RegisterNS(xsiNS, "i")
+	r.rootNode.RegisterNS(envelopeNS, "s")
 
 	// attach header
-	r.header = r.rootNode.NewChildren("Header", "http://schemas.xmlsoap.org/soap/envelope/")
+	r.header = r.rootNode.NewChildren("Header", envelopeNS)
 	r.header.SetDefaultNS(r.client.targetNamespace)
 
-	// attach body
-	r.body = r.rootNode.NewChildren("Body", "http://schemas.xmlsoap.org/soap/envelope/")
-	r.body.SetDefaultNS(r.client.targetNamespace)
+	// attach body; no default namespace, elements are qualified as their schema defines
+	r.body = r.rootNode.NewChildren("Body", envelopeNS)
 }
 
 // SetInputHeader sets the specified input header value
@@ -72,38 +84,130 @@ func (r *Request) SetBodyValues(body *dom.Document) {
 
 // XML returns the XML data for this Request
 func (r *Request) XML() string {
-	r.build()
-	return r.envelope.XML()
+	result, err := r.XMLE()
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+// XMLE returns the XML data for this Request, returning an error instead of panicking
+func (r *Request) XMLE() (string, error) {
+	if err := r.build(); err != nil {
+		return "", err
+	}
+	if r.signature != nil {
+		if err := r.buildSignature(r.envelope); err != nil {
+			return "", err
+		}
+	}
+	data, err := r.serialize(r.envelope)
+	return string(data), err
+}
+
+// serialize returns the XML to send; signed envelopes are sent in canonical form so the digests match
+func (r *Request) serialize(envelope *dom.Document) ([]byte, error) {
+	if r.signature == nil {
+		return []byte(envelope.XML()), nil
+	}
+	canonical, err := envelope.Root.Canonicalize(dom.ExclusiveC14N, nil)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), canonical...), nil
 }
 
 // Send sends the request
 func (r *Request) Send() *Response {
-	body := bytes.NewBufferString(r.XML())
-
-	httpRequest, err := http.NewRequest("POST", r.service.url, body)
+	response, err := r.SendE()
 	if err != nil {
 		panic(err)
 	}
+	return response
+}
+
+// SendE sends the request, returning an error instead of panicking.
+// SOAP faults are not errors here, they are available via Response.Fault()
+func (r *Request) SendE() (*Response, error) {
+	return r.SendContext(context.Background())
+}
+
+// SendContext sends the request through the middleware chain and the Client's Transport,
+// aborting the round-trip once ctx is done.
+// A TransportError wrapping context.DeadlineExceeded is returned if the deadline is exceeded.
+// If a HTTP response was received but cannot be used, it is returned along with a TransportError
+// wrapping ErrHTTPStatus (unexpected status without SOAP fault) or ErrMalformedResponse (no valid envelope)
+func (r *Request) SendContext(ctx context.Context) (*Response, error) {
+	if err := r.build(); err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	r.client.applyHeaders(header)
+	header.Set("Content-Type", r.operation.soapVersion.ContentType(r.GetSOAPAction()))
+	if r.operation.soapVersion == SOAP11 {
+		header.Set("SOAPAction", r.GetSOAPAction())
+	}
 
-	httpRequest.Header.Add("Content-Type", "text/xml; charset=utf-8")
-	httpRequest.Header.Add("SOAPAction", r.GetSOAPAction())
+	return r.chain(r.roundTrip)(ctx, &Call{
+		Request:  r,
+		Envelope: r.envelope,
+		URL:      r.operation.url,
+		Action:   r.GetSOAPAction(),
+		Header:   header,
+	})
+}
 
-	httpResponse, err := r.client.httpClient.Do(httpRequest)
+// roundTrip is the innermost Handler, signing the envelope as modified by the middleware and sending the Call via the Transport
+func (r *Request) roundTrip(ctx context.Context, call *Call) (*Response, error) {
+	if r.signature != nil {
+		if err := r.buildSignature(call.Envelope); err != nil {
+			return nil, err
+		}
+	}
+	envelope, err := r.serialize(call.Envelope)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	raw, err := ioutil.ReadAll(httpResponse.Body)
+	transportResponse, err := r.client.transport.RoundTrip(ctx, &TransportRequest{
+		URL:         call.URL,
+		Action:      call.Action,
+		SOAPVersion: r.operation.soapVersion,
+		Header:      call.Header,
+		Envelope:    envelope,
+	})
 	if err != nil {
-		panic(err)
+		var transportError *TransportError
+		if errors.As(err, &transportError) {
+			return nil, err
+		}
+		return nil, &TransportError{URL: call.URL, Err: contextError(ctx, err)}
+	}
+
+	response, err := newHTTPResponse(transportResponse.StatusCode, transportResponse.Header, transportResponse.Envelope)
+	if response != nil {
+		response.operation = r.operation
+	}
+	if err != nil {
+		return response, &TransportError{URL: call.URL, StatusCode: transportResponse.StatusCode,
+			Body: transportResponse.Envelope, Err: err}
 	}
 
-	return ParseResponse(raw)
+	// faults are usually not signed
+	if r.client.verification != nil && response.Fault() == nil {
+		if err := response.VerifySignature(r.client.verification.certificate); err != nil {
+			return response, err
+		}
+	}
+	return response, nil
 }
 
-func (r *Request) build() {
-	r.buildHeader()
-	r.buildBody()
+func (r *Request) build() error {
+	if err := r.buildHeader(); err != nil {
+		return err
+	}
+	return r.buildBody()
 }
 
 // GetSOAPAction returns the named SOAP action this Request targets
@@ -111,14 +215,16 @@ func (r *Request) GetSOAPAction() string {
 	return r.operation.domNode.XPath("operation").First().GetAttributeValue("soapAction")
 }
 
-func (r *Request) buildHeader() {
+func (r *Request) buildHeader() error {
 	// clear header
 	r.header.Children.ClearAll()
 
-	// write soap action
-	soapAction := r.header.NewChildren("Action", "")
-	soapAction.SetValue(r.GetSOAPAction())
-	soapAction.SetAttribute("", "mustUnderstand", "1")
+	// write WS-Addressing
+	if r.addressing {
+		if err := r.buildAddressing(); err != nil {
+			return err
+		}
+	}
 
 	// write header values
 	inputHeaderList := r.operation.domNode.XPath("input/header")
@@ -129,17 +235,28 @@ func (r *Request) buildHeader() {
 			val.CopyValue(headerNode)
 		}
 	}
+
+	// write WS-Security
+	if r.usernameToken != nil {
+		if err := r.buildUsernameToken(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (r *Request) buildBody() {
+func (r *Request) buildBody() error {
 	// clear body
 	r.body.Children.ClearAll()
 
 	// write body; find message for body
-	_, messageName := dom.SplitFQName(r.operation.domNode.XPath("input").First().GetAttributeValue("message"))
-	message := r.wsdl.XPath("/definitions/message[@name='%s']", messageName).First()
+	message := r.operation.inputMessage()
 	messageElement := message.XPath("part").First().GetAttributeValue("element")
 
 	// find element for message
-	r.wsdl.FindElement(messageElement, message).Build(r.body, r.bodyValues, r.typeExtensions)
+	element, err := r.wsdl.LookupElement(messageElement, message)
+	if err != nil {
+		return err
+	}
+	return element.BuildE(r.body, r.bodyValues, r.typeExtensions)
 }
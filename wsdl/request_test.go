@@ -0,0 +1,149 @@
+package wsdl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lordkhonsu/go-soap/dom"
+)
+
+const testListSchema = `<xs:element name="Request"><xs:complexType><xs:sequence>
+      <xs:element name="Item" minOccurs="0" maxOccurs="unbounded"><xs:complexType><xs:sequence>
+        <xs:element name="Name" type="xs:string"/>
+        <xs:element name="Price" type="xs:decimal"/>
+      </xs:sequence><xs:attribute name="id" type="xs:int"/></xs:complexType></xs:element>
+    </xs:sequence></xs:complexType></xs:element>`
+
+// testListValues returns body values with count items
+func testListValues(count int) *dom.Document {
+	values := dom.NewDocument("Request")
+	for i := 0; i < count; i++ {
+		item := values.Root.NewChildren("Item", "")
+		item.SetAttribute("", "id", fmt.Sprint(i))
+		item.NewChildren("Name", "").SetValue(fmt.Sprintf("item %d", i))
+		item.NewChildren("Price", "").SetValue(i)
+	}
+	return values
+}
+
+func TestRequestListValues(t *testing.T) {
+	client := newTestClient(t, testSchema("", testListSchema))
+	envelope, err := buildTestBody(t, client, testListValues(3), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := envelope.Root.XPath("Body/Request/Item")
+	if items.Len() != 3 {
+		t.Fatalf("expected 3 items, got %d", items.Len())
+	}
+	for i, item := range items.All() {
+		if item.GetAttributeValue("id") != fmt.Sprint(i) || item.XPath("Name").First().String() != fmt.Sprintf("item %d", i) {
+			t.Fatalf("unexpected item %d: %s", i, item.XML())
+		}
+	}
+}
+
+// BenchmarkRequestBuild measures building header and body, which must grow linearly with the values given
+// blockingTransport returns a testTransport answering only once ctx is done, with its error
+func blockingTransport() *testTransport {
+	return &testTransport{respond: func(ctx context.Context, request *TransportRequest) (*TransportResponse, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+}
+
+func TestRequestContext(t *testing.T) {
+	client := newTestClient(t, testSchema("", `<xs:element name="Request" type="xs:string"/>`), WithTransport(blockingTransport()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := newTestRequest(t, client).SendContext(ctx)
+	var transportError *TransportError
+	if !errors.As(err, &transportError) || !transportError.Timeout() || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a timed out TransportError, got [%v]", err)
+	}
+	if transportError.URL != "http://localhost/test" {
+		t.Fatalf("unexpected URL [%s]", transportError.URL)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err = newTestRequest(t, client).SendContext(ctx)
+	if !errors.As(err, &transportError) || transportError.Timeout() || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancelled TransportError, got [%v]", err)
+	}
+}
+
+func TestRequestContextHTTP(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+	client := newTestClient(t, testSchema("", `<xs:element name="Request" type="xs:string"/>`), WithEndpoint(server.URL))
+
+	// the error of the http.Client is replaced by the one of the context
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := newTestRequest(t, client).SendContext(ctx)
+	var transportError *TransportError
+	if !errors.As(err, &transportError) || !transportError.Timeout() || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a timed out TransportError, got [%v]", err)
+	}
+}
+
+// timeoutError is a net.Error timing out
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestTransportErrorTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		timeout bool
+	}{
+		{"deadline", context.DeadlineExceeded, true},
+		{"wrapped deadline", fmt.Errorf("sending: %w", context.DeadlineExceeded), true},
+		{"net timeout", &net.OpError{Op: "read", Err: timeoutError{}}, true},
+		{"cancelled", context.Canceled, false},
+		{"status", ErrHTTPStatus, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := error(&TransportError{URL: "http://localhost/test", Err: test.err})
+			var transportError *TransportError
+			if !errors.As(err, &transportError) || transportError.Timeout() != test.timeout {
+				t.Fatalf("expected Timeout() = %v for [%v]", test.timeout, err)
+			}
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected [%v] to wrap [%v]", err, test.err)
+			}
+		})
+	}
+}
+
+func BenchmarkRequestBuild(b *testing.B) {
+	client := newTestClient(b, testSchema("", testListSchema))
+	for _, count := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("items=%d", count), func(b *testing.B) {
+			request := newTestRequest(b, client)
+			request.SetBodyValues(testListValues(count))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := request.build(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
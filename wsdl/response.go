@@ -1,22 +1,101 @@
 package wsdl
 
 import (
+	"bytes"
 	"encoding/xml"
+	"fmt"
 	"github.com/lordkhonsu/go-soap/dom"
+	"mime"
+	"net/http"
+	"strings"
 )
 
 // Response wraps the received SOAP response
 type Response struct {
-	document *dom.Document
+	document  *dom.Document
+	operation *Operation
+
+	statusCode int
+	httpHeader http.Header
+	raw        []byte
 }
 
 // ParseResponse parses the XML passed in raw and returns a Response
 func ParseResponse(raw []byte) *Response {
+	newResponse, _ := ParseResponseE(raw)
+	return newResponse
+}
+
+// ParseResponseE parses the XML passed in raw and returns a Response, or an error wrapping
+// ErrMalformedResponse (along with the partially parsed Response) if raw is no SOAP envelope
+func ParseResponseE(raw []byte) (*Response, error) {
 	newResponse := &Response{
 		document: dom.NewDocument("response"),
+		raw:      raw,
 	}
-	xml.Unmarshal(raw, newResponse.document)
-	return newResponse
+	if err := xml.Unmarshal(raw, newResponse.document); err != nil {
+		return newResponse, fmt.Errorf("%w: %v", ErrMalformedResponse, err)
+	}
+
+	root := newResponse.document.Root
+	if root.Name != "Envelope" || root.Namespace == nil ||
+		(root.Namespace.Name != soap11EnvelopeNS && root.Namespace.Name != soap12EnvelopeNS) {
+		return newResponse, fmt.Errorf("%w: root element [%s] is no SOAP envelope", ErrMalformedResponse, root.Name)
+	}
+	return newResponse, nil
+}
+
+// newHTTPResponse evaluates a HTTP response: SOAP faults are accepted with any status, a non-2xx status
+// without fault results in ErrHTTPStatus, and bodies which are no SOAP envelope result in ErrMalformedResponse
+func newHTTPResponse(statusCode int, header http.Header, raw []byte) (*Response, error) {
+	// non-HTTP transports may not report any status
+	success := statusCode == 0 || (statusCode >= 200 && statusCode <= 299)
+
+	// one-way operations may answer with an empty body
+	if success && len(bytes.TrimSpace(raw)) == 0 {
+		newResponse := &Response{document: dom.NewDocument("response"), raw: raw}
+		newResponse.statusCode, newResponse.httpHeader = statusCode, header
+		return newResponse, nil
+	}
+
+	var newResponse *Response
+	var err error
+	if isXMLContent(header.Get("Content-Type"), raw) {
+		newResponse, err = ParseResponseE(raw)
+	} else {
+		newResponse = &Response{document: dom.NewDocument("response"), raw: raw}
+		err = fmt.Errorf("%w: unexpected content type [%s]", ErrMalformedResponse, header.Get("Content-Type"))
+	}
+	newResponse.statusCode, newResponse.httpHeader = statusCode, header
+
+	if !success && (err != nil || newResponse.Fault() == nil) {
+		return newResponse, ErrHTTPStatus
+	}
+	return newResponse, err
+}
+
+// isXMLContent checks the content type and the body for XML (servers often send XML as text/plain)
+func isXMLContent(contentType string, raw []byte) bool {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil &&
+		(strings.HasSuffix(mediaType, "/xml") || strings.HasSuffix(mediaType, "+xml")) {
+		return true
+	}
+	return bytes.HasPrefix(bytes.TrimSpace(raw), []byte("<"))
+}
+
+// StatusCode returns the HTTP status code of the response (0 if not received via HTTP)
+func (r *Response) StatusCode() int {
+	return r.statusCode
+}
+
+// HTTPHeader returns the HTTP headers of the response (nil if not received via HTTP)
+func (r *Response) HTTPHeader() http.Header {
+	return r.httpHeader
+}
+
+// Raw returns the raw response body
+func (r *Response) Raw() []byte {
+	return r.raw
 }
 
 // XML returns the Response encoded as XML
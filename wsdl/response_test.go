@@ -0,0 +1,59 @@
+package wsdl
+
+import (
+	"errors"
+	"testing"
+)
+
+const testFaultEnvelope = `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><s:Fault>` +
+	`<faultcode>s:Server</faultcode><faultstring>failed</faultstring></s:Fault></s:Body></s:Envelope>`
+
+func TestResponseStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		statusCode  int
+		contentType string
+		body        string
+		fault       bool
+		err         error
+	}{
+		{"success", 200, "text/xml; charset=utf-8", testResponseEnvelope, false, nil},
+		{"XML as text/plain", 200, "text/plain", testResponseEnvelope, false, nil},
+		{"empty one-way response", 202, "", "", false, nil},
+		{"fault with 500", 500, "text/xml", testFaultEnvelope, true, nil},
+		{"fault with 200", 200, "application/soap+xml", testFaultEnvelope, true, nil},
+		{"HTML error page", 502, "text/html", "<html><body>Bad Gateway</body></html>", false, ErrHTTPStatus},
+		{"500 without fault", 500, "text/xml", testResponseEnvelope, false, ErrHTTPStatus},
+		{"non-XML body", 200, "text/plain", "OK", false, ErrMalformedResponse},
+		{"malformed XML", 200, "text/xml", "<s:Envelope", false, ErrMalformedResponse},
+		{"no envelope", 200, "text/xml", "<html/>", false, ErrMalformedResponse},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := newTestClient(t, testSchema("", `<xs:element name="Request" type="xs:string"/>`),
+				WithTransport(respondWith(test.statusCode, test.contentType, test.body)))
+			response, err := newTestRequest(t, client).SendE()
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected [%v], got [%v]", test.err, err)
+			}
+
+			// the response is available in any case, errors are TransportErrors carrying status and body
+			if response == nil {
+				t.Fatal("expected a response")
+			}
+			if response.StatusCode() != test.statusCode || string(response.Raw()) != test.body {
+				t.Fatalf("unexpected status %d or body %s", response.StatusCode(), response.Raw())
+			}
+			if (response.Fault() != nil) != test.fault {
+				t.Fatalf("expected fault = %v", test.fault)
+			}
+			if err != nil {
+				var transportError *TransportError
+				if !errors.As(err, &transportError) || transportError.StatusCode != test.statusCode ||
+					string(transportError.Body) != test.body {
+					t.Fatalf("expected a TransportError with status and body, got [%v]", err)
+				}
+			}
+		})
+	}
+}
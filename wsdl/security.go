@@ -0,0 +1,105 @@
+package wsdl
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"time"
+
+	"github.com/lordkhonsu/go-soap/dom"
+)
+
+const (
+	wsseNS = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"
+	wsuNS  = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"
+
+	wssUsernameTokenProfile = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0"
+	wssSOAPMessageSecurity  = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0"
+)
+
+// PasswordType defines how the password of a WS-Security UsernameToken is transmitted
+type PasswordType int
+
+const (
+	// PasswordText transmits the password in clear text
+	PasswordText PasswordType = iota
+	// PasswordDigest transmits Base64(SHA-1(nonce + created + password))
+	PasswordDigest
+)
+
+type usernameToken struct {
+	username     string
+	password     string
+	passwordType PasswordType
+}
+
+// WithUsernameToken adds a WS-Security UsernameToken to all requests of the Client
+func WithUsernameToken(username string, password string, passwordType PasswordType) Option {
+	return func(c *Client) {
+		c.usernameToken = &usernameToken{
+			username:     username,
+			password:     password,
+			passwordType: passwordType,
+		}
+	}
+}
+
+// SetUsernameToken adds a WS-Security UsernameToken to this Request, replacing the one of the Client
+func (r *Request) SetUsernameToken(username string, password string, passwordType PasswordType) {
+	r.usernameToken = &usernameToken{
+		username:     username,
+		password:     password,
+		passwordType: passwordType,
+	}
+}
+
+// securityHeader returns the <wsse:Security> block of the envelope's header, creating it if necessary
+func (r *Request) securityHeader(envelope *dom.Node, header *dom.Node) *dom.Node {
+	if security := header.XPath("Security").First(); security.Exists {
+		return security
+	}
+	// declared on the envelope, as the body may need a wsu:Id as well
+	envelope.RegisterNS(wsseNS, "wsse")
+	envelope.RegisterNS(wsuNS, "wsu")
+
+	security := header.NewChildren("Security", wsseNS)
+	security.SetAttribute(r.operation.soapVersion.EnvelopeNamespace(), "mustUnderstand", "1")
+	return security
+}
+
+func (r *Request) buildUsernameToken() error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	created := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+
+	token := r.securityHeader(r.rootNode, r.header).NewChildren("UsernameToken", wsseNS)
+	token.SetAttribute(wsuNS, "Id", "UsernameToken-1")
+	token.NewChildren("Username", wsseNS).SetValue(r.usernameToken.username)
+
+	password := token.NewChildren("Password", wsseNS)
+	if r.usernameToken.passwordType == PasswordDigest {
+		password.SetAttribute("", "Type", wssUsernameTokenProfile+"#PasswordDigest")
+		password.SetValue(passwordDigest(nonce, created, r.usernameToken.password))
+	} else {
+		password.SetAttribute("", "Type", wssUsernameTokenProfile+"#PasswordText")
+		password.SetValue(r.usernameToken.password)
+	}
+
+	encodedNonce := token.NewChildren("Nonce", wsseNS)
+	encodedNonce.SetAttribute("", "EncodingType", wssSOAPMessageSecurity+"#Base64Binary")
+	encodedNonce.SetValue(base64.StdEncoding.EncodeToString(nonce))
+
+	token.NewChildren("Created", wsuNS).SetValue(created)
+	return nil
+}
+
+// passwordDigest computes the UsernameToken digest: Base64(SHA-1(nonce + created + password))
+func passwordDigest(nonce []byte, created string, password string) string {
+	hash := sha1.New()
+	hash.Write(nonce)
+	hash.Write([]byte(created))
+	hash.Write([]byte(password))
+	return base64.StdEncoding.EncodeToString(hash.Sum(nil))
+}
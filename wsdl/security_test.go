@@ -0,0 +1,87 @@
+package wsdl
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lordkhonsu/go-soap/dom"
+)
+
+func TestUsernameToken(t *testing.T) {
+	schemas := testSchema("", `<xs:element name="Request" type="xs:string"/>`)
+
+	tests := []struct {
+		name        string
+		definitions string
+		options     []Option
+		request     func(request *Request)
+		username    string
+		password    string
+		digest      bool
+	}{
+		{"text", testDefinitions(schemas), []Option{WithUsernameToken("user", "secret", PasswordText)}, nil,
+			"user", "secret", false},
+		{"digest", testDefinitions(schemas), []Option{WithUsernameToken("user", "secret", PasswordDigest)}, nil,
+			"user", "secret", true},
+		{"digest with SOAP 1.2", testDefinitions12(schemas), []Option{WithUsernameToken("user", "secret", PasswordDigest)}, nil,
+			"user", "secret", true},
+		{"request replacing the client's", testDefinitions(schemas), []Option{WithUsernameToken("user", "secret", PasswordText)},
+			func(request *Request) { request.SetUsernameToken("other", "password", PasswordDigest) },
+			"other", "password", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transport := &testTransport{}
+			client, err := NewClientFromReader(strings.NewReader(test.definitions), append(test.options, WithTransport(transport))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			request := newTestRequest(t, client)
+			if test.request != nil {
+				test.request(request)
+			}
+			if _, err := request.SendE(); err != nil {
+				t.Fatal(err)
+			}
+
+			sent := transport.requests[0]
+			envelope, err := dom.Parse(sent.Envelope)
+			if err != nil {
+				t.Fatal(err)
+			}
+			namespaces := map[string]string{"s": sent.SOAPVersion.EnvelopeNamespace(), "wsse": wsseNS, "wsu": wsuNS}
+			security := envelope.Root.XPathNS("s:Header/wsse:Security", namespaces).All()
+			if len(security) != 1 {
+				t.Fatalf("expected a single Security header, got %s", sent.Envelope)
+			}
+			if attr, exists := security[0].GetAttributeNS(sent.SOAPVersion.EnvelopeNamespace(), "mustUnderstand"); !exists || attr.Value != "1" {
+				t.Fatalf("expected mustUnderstand in the envelope namespace, got %s", sent.Envelope)
+			}
+
+			token := security[0].XPathNS("wsse:UsernameToken", namespaces).First()
+			username := token.XPathNS("wsse:Username", namespaces).First().String()
+			password := token.XPathNS("wsse:Password", namespaces).First()
+			nonce, err := base64.StdEncoding.DecodeString(token.XPathNS("wsse:Nonce", namespaces).First().String())
+			if err != nil || len(nonce) != 16 {
+				t.Fatalf("expected a 16 byte nonce, got %s", sent.Envelope)
+			}
+			created := token.XPathNS("wsu:Created", namespaces).First().String()
+			if at, err := time.Parse(time.RFC3339, created); err != nil || time.Since(at) > time.Minute {
+				t.Fatalf("expected the current time as Created, got [%s]", created)
+			}
+
+			expected, passwordType := test.password, "#PasswordText"
+			if test.digest {
+				hash := sha1.Sum([]byte(string(nonce) + created + test.password))
+				expected, passwordType = base64.StdEncoding.EncodeToString(hash[:]), "#PasswordDigest"
+			}
+			if username != test.username || password.String() != expected ||
+				!strings.HasSuffix(password.GetAttributeValue("Type"), passwordType) {
+				t.Fatalf("expected %s with %s [%s], got %s", test.username, passwordType, expected, sent.Envelope)
+			}
+		})
+	}
+}
@@ -13,27 +13,89 @@ type Service struct {
 	wsdl       *WSDL
 	name       string
 	url        string
+	domNode    *dom.Node
 	operations map[string]*Operation
 }
 
 func (s *Service) init() {
 	// map all operations via binding ports
 	s.operations = map[string]*Operation{}
-	ports := s.client.wsdl.XPath("/definitions/service[@name='%s']/port", s.name)
-	for _, port := range ports.All() {
-		_, bindingName := dom.SplitFQName(port.GetAttributeValue("binding"))
-		operations := s.client.wsdl.XPath("/definitions/binding[@name='%s']/operation", bindingName)
+	for i, port := range s.selectPorts() {
+		url := s.client.endpointURL(s.name, port.domNode.XPath("address").First().GetAttributeValue("location"))
+		if i == 0 {
+			s.url = url
+		}
+
+		addressing := s.client.addressing == AddressingEnabled ||
+			(s.client.addressing == AddressingAuto && s.wsdl.usesAddressing(port.binding, port.domNode))
+
+		_, portTypeName := dom.SplitFQName(port.binding.GetAttributeValue("type"))
+		portType := s.wsdl.findDefinition("portType", portTypeName)
+		operations := port.binding.XPath("operation")
 		for _, operation := range operations.All() {
 			name := operation.GetAttributeValue("name")
 			s.operations[name] = &Operation{
-				client:  s.client,
-				service: s,
-				wsdl:    s.wsdl,
-				name:    name,
-				domNode: operation,
+				client:       s.client,
+				service:      s,
+				wsdl:         s.wsdl,
+				name:         name,
+				url:          url,
+				soapVersion:  port.soapVersion,
+				addressing:   addressing,
+				domNode:      operation,
+				portTypeNode: portType.XPath("operation[@name='%s']", name).First(),
+			}
+		}
+	}
+}
+
+type servicePort struct {
+	domNode     *dom.Node
+	binding     *dom.Node
+	soapVersion SOAPVersion
+}
+
+// selectPorts returns the ports to use: only SOAP ports of a single version, the forced one or the first one found
+func (s *Service) selectPorts() []*servicePort {
+	all := []*servicePort{}
+	for _, port := range s.domNode.XPath("port").All() {
+		_, bindingName := dom.SplitFQName(port.GetAttributeValue("binding"))
+		binding := s.wsdl.findDefinition("binding", bindingName)
+		all = append(all, &servicePort{
+			domNode:     port,
+			binding:     binding,
+			soapVersion: bindingVersion(binding),
+		})
+	}
+
+	version := s.client.soapVersion
+	if version == SOAPVersionAuto {
+		for _, port := range all {
+			if port.soapVersion != SOAPVersionAuto {
+				version = port.soapVersion
+				break
 			}
 		}
 	}
+
+	selected := []*servicePort{}
+	for _, port := range all {
+		if port.soapVersion == version {
+			selected = append(selected, port)
+		}
+	}
+
+	// the WSDL doesn't offer the version (or any SOAP binding at all) -> use all ports with the requested version
+	if len(selected) == 0 {
+		if version == SOAPVersionAuto {
+			version = SOAP11
+		}
+		for _, port := range all {
+			port.soapVersion = version
+		}
+		selected = all
+	}
+	return selected
 }
 
 // Explain outputs all available operations
@@ -52,8 +114,17 @@ func (s *Service) explain(indent int) {
 
 // Operation returns the named Operation
 func (s *Service) Operation(name string) *Operation {
+	operation, err := s.LookupOperation(name)
+	if err != nil {
+		panic(err)
+	}
+	return operation
+}
+
+// LookupOperation returns the named Operation, or an error wrapping ErrUnknownOperation
+func (s *Service) LookupOperation(name string) (*Operation, error) {
 	if operation, exists := s.operations[name]; exists {
-		return operation
+		return operation, nil
 	}
-	panic(fmt.Errorf("Operation(): unknown operation with name [%s]", name))
+	return nil, fmt.Errorf("Operation(): %w with name [%s]", ErrUnknownOperation, name)
 }
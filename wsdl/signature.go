@@ -0,0 +1,376 @@
+package wsdl
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/lordkhonsu/go-soap/dom"
+)
+
+const (
+	dsNS = "http://www.w3.org/2000/09/xmldsig#"
+
+	envelopedSigAlgo  = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+	wssX509v3         = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-x509-token-profile-1.0#X509v3"
+	signatureLifetime = 5 * time.Minute
+	// signatureClockSkew is tolerated between the clocks of sender and receiver when checking a Timestamp
+	signatureClockSkew = time.Minute
+)
+
+// timeNow returns the current time, replaced by tests
+var timeNow = time.Now
+
+var (
+	digestAlgorithms = map[string]crypto.Hash{
+		"http://www.w3.org/2000/09/xmldsig#sha1":  crypto.SHA1,
+		"http://www.w3.org/2001/04/xmlenc#sha256": crypto.SHA256,
+		"http://www.w3.org/2001/04/xmlenc#sha512": crypto.SHA512,
+	}
+	signatureAlgorithms = map[string]crypto.Hash{
+		"http://www.w3.org/2000/09/xmldsig#rsa-sha1":          crypto.SHA1,
+		"http://www.w3.org/2001/04/xmldsig-more#rsa-sha256":   crypto.SHA256,
+		"http://www.w3.org/2001/04/xmldsig-more#rsa-sha512":   crypto.SHA512,
+		"http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha1":   crypto.SHA1,
+		"http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256": crypto.SHA256,
+		"http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha512": crypto.SHA512,
+	}
+)
+
+type x509Signature struct {
+	certificate *x509.Certificate
+	key         crypto.Signer
+}
+
+type signatureVerification struct {
+	certificate *x509.Certificate
+}
+
+// WithX509Signature signs Body and Timestamp of all requests with the given RSA or ECDSA key,
+// embedding the certificate as BinarySecurityToken
+func WithX509Signature(certificate *x509.Certificate, key crypto.Signer) Option {
+	return func(c *Client) {
+		c.signature = &x509Signature{certificate: certificate, key: key}
+	}
+}
+
+// WithSignatureVerification verifies the signature of all responses (except faults) against the given certificate,
+// which is required: the certificate embedded in a response is controlled by its sender and proves nothing
+func WithSignatureVerification(certificate *x509.Certificate) Option {
+	return func(c *Client) {
+		c.verification = &signatureVerification{certificate: certificate}
+	}
+}
+
+// SetX509Signature signs Body and Timestamp of this Request, replacing the signature settings of the Client
+func (r *Request) SetX509Signature(certificate *x509.Certificate, key crypto.Signer) {
+	r.signature = &x509Signature{certificate: certificate, key: key}
+}
+
+// buildSignature adds Timestamp, BinarySecurityToken and Signature to the security header of the envelope,
+// replacing those of a previous signature; must run after all changes to the envelope
+func (r *Request) buildSignature(envelope *dom.Document) error {
+	header := envelope.Root.XPath("Header").First()
+	body := envelope.Root.XPath("Body").First()
+	if !header.Exists || !body.Exists {
+		return fmt.Errorf("SendE(): envelope has no Header or Body to sign")
+	}
+
+	security := r.securityHeader(envelope.Root, header)
+	kept := security.Children.All()
+	security.Children.ClearAll()
+	for _, child := range kept {
+		if child.Name != "Timestamp" && child.Name != "BinarySecurityToken" && child.Name != "Signature" {
+			security.Children.Append(child)
+		}
+	}
+	now := timeNow().UTC()
+
+	timestamp := security.NewChildren("Timestamp", wsuNS)
+	timestamp.SetAttribute(wsuNS, "Id", "TS-1")
+	timestamp.NewChildren("Created", wsuNS).SetValue(now.Format("2006-01-02T15:04:05.000Z"))
+	timestamp.NewChildren("Expires", wsuNS).SetValue(now.Add(signatureLifetime).Format("2006-01-02T15:04:05.000Z"))
+
+	token := security.NewChildren("BinarySecurityToken", wsseNS)
+	token.SetAttribute("", "EncodingType", wssSOAPMessageSecurity+"#Base64Binary")
+	token.SetAttribute("", "ValueType", wssX509v3)
+	token.SetAttribute(wsuNS, "Id", "X509-1")
+	token.SetValue(base64.StdEncoding.EncodeToString(r.signature.certificate.Raw))
+
+	body.SetAttribute(wsuNS, "Id", "Body-1")
+
+	signatureMethod, err := signatureMethodFor(r.signature.key)
+	if err != nil {
+		return err
+	}
+
+	security.RegisterNS(dsNS, "ds")
+	signature := security.NewChildren("Signature", dsNS)
+	signature.SetAttribute("", "Id", "SIG-1")
+	signedInfo := signature.NewChildren("SignedInfo", dsNS)
+	signedInfo.NewChildren("CanonicalizationMethod", dsNS).SetAttribute("", "Algorithm", dom.ExclusiveC14N.Algorithm())
+	signedInfo.NewChildren("SignatureMethod", dsNS).SetAttribute("", "Algorithm", signatureMethod)
+
+	for _, target := range []*dom.Node{timestamp, body} {
+		canonical, err := target.Canonicalize(dom.ExclusiveC14N, nil)
+		if err != nil {
+			return err
+		}
+		digest := crypto.SHA256.New()
+		digest.Write(canonical)
+
+		reference := signedInfo.NewChildren("Reference", dsNS)
+		reference.SetAttribute("", "URI", "#"+target.GetAttributeValue("Id"))
+		reference.NewChildren("Transforms", dsNS).NewChildren("Transform", dsNS).SetAttribute("", "Algorithm", dom.ExclusiveC14N.Algorithm())
+		reference.NewChildren("DigestMethod", dsNS).SetAttribute("", "Algorithm", "http://www.w3.org/2001/04/xmlenc#sha256")
+		reference.NewChildren("DigestValue", dsNS).SetValue(base64.StdEncoding.EncodeToString(digest.Sum(nil)))
+	}
+
+	canonical, err := signedInfo.Canonicalize(dom.ExclusiveC14N, nil)
+	if err != nil {
+		return err
+	}
+	signatureValue, err := signDigest(r.signature.key, canonical)
+	if err != nil {
+		return err
+	}
+	signature.NewChildren("SignatureValue", dsNS).SetValue(base64.StdEncoding.EncodeToString(signatureValue))
+
+	tokenReference := signature.NewChildren("KeyInfo", dsNS).NewChildren("SecurityTokenReference", wsseNS)
+	reference := tokenReference.NewChildren("Reference", wsseNS)
+	reference.SetAttribute("", "URI", "#X509-1")
+	reference.SetAttribute("", "ValueType", wssX509v3)
+	return nil
+}
+
+func signatureMethodFor(key crypto.Signer) (string, error) {
+	switch key.Public().(type) {
+	case *rsa.PublicKey:
+		return "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256", nil
+	case *ecdsa.PublicKey:
+		return "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256", nil
+	}
+	return "", fmt.Errorf("SetX509Signature(): unsupported key type [%T]", key.Public())
+}
+
+// signDigest signs the SHA-256 digest of data; ECDSA signatures are converted from ASN.1 to r||s as required by XML DSig
+func signDigest(key crypto.Signer, data []byte) ([]byte, error) {
+	digest := crypto.SHA256.New()
+	digest.Write(data)
+	signature, err := key.Sign(rand.Reader, digest.Sum(nil), crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, ok := key.Public().(*ecdsa.PublicKey)
+	if !ok {
+		return signature, nil
+	}
+	var parsed struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(signature, &parsed); err != nil {
+		return nil, err
+	}
+	size := (publicKey.Curve.Params().BitSize + 7) / 8
+	result := make([]byte, 2*size)
+	parsed.R.FillBytes(result[:size])
+	parsed.S.FillBytes(result[size:])
+	return result, nil
+}
+
+// VerifySignature verifies the WS-Security XML signature of the Response against certificate, which must cover
+// the Body and the Timestamp if there is one. Expired Timestamps and those created in the future are rejected
+func (r *Response) VerifySignature(certificate *x509.Certificate) error {
+	if certificate == nil {
+		return fmt.Errorf("%w: no certificate to verify against", ErrInvalidSignature)
+	}
+	signature := r.Header().XPath("Security/Signature").First()
+	if !signature.Exists {
+		return fmt.Errorf("%w: response is not signed", ErrInvalidSignature)
+	}
+	signedInfo := signature.XPath("SignedInfo").First()
+
+	// signed info
+	canonicalization := signedInfo.XPath("CanonicalizationMethod").First()
+	algorithm := canonicalization.GetAttributeValue("Algorithm")
+	mode, supported := dom.ParseCanonicalizationAlgorithm(algorithm)
+	if !supported {
+		return fmt.Errorf("%w: unsupported canonicalization [%s]", ErrInvalidSignature, algorithm)
+	}
+	canonicalSignedInfo, err := r.canonicalElement(mode, inclusivePrefixes(canonicalization), func(namespace string, name string, attributes []xml.Attr) bool {
+		return namespace == dsNS && name == "SignedInfo"
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	// references, the body must be one of them
+	signed := map[string]bool{}
+	for _, reference := range signedInfo.XPath("Reference").All() {
+		id := strings.TrimPrefix(reference.GetAttributeValue("URI"), "#")
+		if err := r.verifyReference(signature, reference, id); err != nil {
+			return err
+		}
+		signed[id] = id != ""
+	}
+	if !signed[r.Body().GetAttributeValue("Id")] {
+		return fmt.Errorf("%w: body is not signed", ErrInvalidSignature)
+	}
+	if err := r.checkTimestamp(signed, timeNow()); err != nil {
+		return err
+	}
+
+	// signature value
+	hash, exists := signatureAlgorithms[signedInfo.XPath("SignatureMethod").First().GetAttributeValue("Algorithm")]
+	if !exists {
+		return fmt.Errorf("%w: unsupported signature method", ErrInvalidSignature)
+	}
+	signatureValue, err := decodeBase64(signature.XPath("SignatureValue").First().String())
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	digest := hash.New()
+	digest.Write(canonicalSignedInfo)
+
+	switch publicKey := certificate.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(publicKey, hash, digest.Sum(nil), signatureValue); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+		}
+	case *ecdsa.PublicKey:
+		size := len(signatureValue) / 2
+		r, s := new(big.Int).SetBytes(signatureValue[:size]), new(big.Int).SetBytes(signatureValue[size:])
+		if !ecdsa.Verify(publicKey, digest.Sum(nil), r, s) {
+			return fmt.Errorf("%w: ECDSA verification failed", ErrInvalidSignature)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported key type [%T]", ErrInvalidSignature, certificate.PublicKey)
+	}
+	return nil
+}
+
+// checkTimestamp checks the signed wsu:Timestamp (if any) of the security header, allowing for signatureClockSkew
+func (r *Response) checkTimestamp(signed map[string]bool, now time.Time) error {
+	timestamps := r.Header().XPath("Security/Timestamp")
+	if timestamps.Len() == 0 {
+		return nil
+	}
+	timestamp := timestamps.First()
+	if timestamps.Len() > 1 || !signed[timestamp.GetAttributeValue("Id")] {
+		return fmt.Errorf("%w: timestamp is not signed", ErrInvalidSignature)
+	}
+
+	if created := timestamp.XPath("Created").First(); created.Exists {
+		createdAt, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(created.String()))
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+		}
+		if createdAt.After(now.Add(signatureClockSkew)) {
+			return fmt.Errorf("%w: timestamp created in the future at [%s]", ErrInvalidSignature, created.String())
+		}
+	}
+	if expires := timestamp.XPath("Expires").First(); expires.Exists {
+		expiresAt, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(expires.String()))
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+		}
+		if expiresAt.Before(now.Add(-signatureClockSkew)) {
+			return fmt.Errorf("%w: timestamp expired at [%s]", ErrInvalidSignature, expires.String())
+		}
+	}
+	return nil
+}
+
+func (r *Response) verifyReference(signature *dom.Node, reference *dom.Node, id string) error {
+	// same-document references default to C14N 1.0 without comments
+	mode, prefixes := dom.C14N10, []string(nil)
+	var exclude dom.CanonicalMatcher
+	for _, transform := range reference.XPath("Transforms/Transform").All() {
+		algorithm := transform.GetAttributeValue("Algorithm")
+		if algorithm == envelopedSigAlgo {
+			// the signature itself is removed from the referenced element before canonicalization
+			exclude = signatureMatcher(signature.GetAttributeValue("Id"))
+			continue
+		}
+		transformMode, supported := dom.ParseCanonicalizationAlgorithm(algorithm)
+		if !supported {
+			return fmt.Errorf("%w: unsupported transform [%s]", ErrInvalidSignature, algorithm)
+		}
+		mode, prefixes = transformMode, inclusivePrefixes(transform)
+	}
+
+	canonical, err := r.canonicalElement(mode, prefixes, func(namespace string, name string, attributes []xml.Attr) bool {
+		for _, attr := range attributes {
+			if (attr.Name.Local == "Id" || attr.Name.Local == "ID") && attr.Value == id {
+				return true
+			}
+		}
+		return false
+	}, exclude)
+	if err != nil {
+		return err
+	}
+
+	hash, exists := digestAlgorithms[reference.XPath("DigestMethod").First().GetAttributeValue("Algorithm")]
+	if !exists {
+		return fmt.Errorf("%w: unsupported digest method for [#%s]", ErrInvalidSignature, id)
+	}
+	digest := hash.New()
+	digest.Write(canonical)
+	if base64.StdEncoding.EncodeToString(digest.Sum(nil)) != stripWhitespace(reference.XPath("DigestValue").First().String()) {
+		return fmt.Errorf("%w: digest mismatch for [#%s]", ErrInvalidSignature, id)
+	}
+	return nil
+}
+
+// signatureMatcher selects the ds:Signature with the given Id, any ds:Signature if the signature has no Id
+func signatureMatcher(id string) dom.CanonicalMatcher {
+	return func(namespace string, name string, attributes []xml.Attr) bool {
+		if namespace != dsNS || name != "Signature" {
+			return false
+		}
+		if id == "" {
+			return true
+		}
+		for _, attr := range attributes {
+			if attr.Name.Local == "Id" && attr.Value == id {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// canonicalElement canonicalizes exactly one element of the raw response, more matches hint at a wrapping attack;
+// descendants selected by exclude are left out
+func (r *Response) canonicalElement(mode dom.CanonicalizationMode, prefixes []string, match dom.CanonicalMatcher, exclude dom.CanonicalMatcher) ([]byte, error) {
+	result, err := dom.CanonicalizeXMLExcluding(r.raw, mode, prefixes, match, exclude)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	if len(result) != 1 {
+		return nil, fmt.Errorf("%w: expected exactly one signed element, found %d", ErrInvalidSignature, len(result))
+	}
+	return result[0], nil
+}
+
+// inclusivePrefixes reads the InclusiveNamespaces PrefixList of an exclusive canonicalization
+func inclusivePrefixes(node *dom.Node) []string {
+	return strings.Fields(node.XPath("InclusiveNamespaces").First().GetAttributeValue("PrefixList"))
+}
+
+func decodeBase64(encoded string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(stripWhitespace(encoded))
+}
+
+func stripWhitespace(in string) string {
+	return strings.Join(strings.Fields(in), "")
+}
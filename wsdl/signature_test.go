@@ -0,0 +1,277 @@
+package wsdl
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lordkhonsu/go-soap/dom"
+)
+
+const testSignatureSchema = `<xs:element name="Request"><xs:complexType><xs:sequence>
+      <xs:element name="Name" type="xs:string"/>
+    </xs:sequence></xs:complexType></xs:element>`
+
+// loopbackTransport answers every request with the envelope it was sent
+type loopbackTransport struct{}
+
+func (loopbackTransport) RoundTrip(ctx context.Context, request *TransportRequest) (*TransportResponse, error) {
+	return &TransportResponse{StatusCode: 200, Envelope: request.Envelope}, nil
+}
+
+func testCertificate(t *testing.T, key crypto.Signer) *x509.Certificate {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "go-soap test"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certificate
+}
+
+func testKeys(t *testing.T) map[string]crypto.Signer {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]crypto.Signer{"RSA": rsaKey, "ECDSA": ecdsaKey}
+}
+
+func TestSignatureRoundTrip(t *testing.T) {
+	for name, key := range testKeys(t) {
+		t.Run(name, func(t *testing.T) {
+			certificate := testCertificate(t, key)
+			client := newTestClient(t, testSchema(`elementFormDefault="qualified"`, testSignatureSchema),
+				WithTransport(loopbackTransport{}),
+				WithX509Signature(certificate, key),
+				WithSignatureVerification(certificate),
+			)
+
+			request := newTestRequest(t, client)
+			request.SetBodyValues(dom.Convert("Request", dom.Map{"Name": "a&b"}))
+			// changes of middleware are signed, sending twice replaces the previous signature
+			request.Use(func(next Handler) Handler {
+				return func(ctx context.Context, call *Call) (*Response, error) {
+					call.Envelope.Root.XPath("Body/Request/Name").First().SetValue("<changed>")
+					if _, err := next(ctx, call); err != nil {
+						return nil, err
+					}
+					return next(ctx, call)
+				}
+			})
+
+			response, err := request.SendE()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if value := response.Body().XPath("Request/Name").First().String(); value != "<changed>" {
+				t.Fatalf("expected the value of the middleware, got [%s]", value)
+			}
+			if count := response.Header().XPath("Security/Signature").Len(); count != 1 {
+				t.Fatalf("expected one signature, got %d", count)
+			}
+		})
+	}
+}
+
+func TestSignatureTampering(t *testing.T) {
+	keys := testKeys(t)
+	certificate := testCertificate(t, keys["RSA"])
+	client := newTestClient(t, testSchema(`elementFormDefault="qualified"`, testSignatureSchema),
+		WithX509Signature(certificate, keys["RSA"]))
+	request := newTestRequest(t, client)
+	request.SetBodyValues(dom.Convert("Request", dom.Map{"Name": "original"}))
+	signed, err := request.XMLE()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		tamper      func(string) string
+		certificate *x509.Certificate
+		err         error
+	}{
+		{"unchanged", func(in string) string { return in }, certificate, nil},
+		// the embedded certificate is controlled by the sender
+		{"no certificate", func(in string) string { return in }, nil, ErrInvalidSignature},
+		{"body", func(in string) string { return strings.Replace(in, "original", "forged", 1) }, certificate, ErrInvalidSignature},
+		{"timestamp", func(in string) string { return strings.Replace(in, "<wsu:Expires>2", "<wsu:Expires>3", 1) }, certificate, ErrInvalidSignature},
+		{"signed info", func(in string) string { return strings.Replace(in, `URI="#TS-1"`, `URI="#X509-1"`, 1) }, certificate, ErrInvalidSignature},
+		{"other certificate", func(in string) string { return in }, testCertificate(t, keys["ECDSA"]), ErrInvalidSignature},
+		{"wrapped body", func(in string) string {
+			body := in[strings.Index(in, "<s:Body"):strings.Index(in, "</s:Body>")]
+			return strings.Replace(in, "</s:Header>", "<Wrapper>"+body+"</s:Body></Wrapper></s:Header>", 1)
+		}, certificate, ErrInvalidSignature},
+		{"wrapped timestamp", func(in string) string {
+			timestamp := in[strings.Index(in, "<wsu:Timestamp"):strings.Index(in, "</wsu:Timestamp>")]
+			fresh := `<wsu:Timestamp><wsu:Created>2000-01-01T00:00:00Z</wsu:Created></wsu:Timestamp>`
+			in = strings.Replace(in, timestamp+"</wsu:Timestamp>", fresh, 1)
+			return strings.Replace(in, "</s:Header>", "<Wrapper>"+timestamp+"</wsu:Timestamp></Wrapper></s:Header>", 1)
+		}, certificate, ErrInvalidSignature},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := ParseResponseE([]byte(test.tamper(signed)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := response.VerifySignature(test.certificate); !errors.Is(err, test.err) {
+				t.Fatalf("expected [%v], got [%v]", test.err, err)
+			}
+		})
+	}
+}
+
+const testEnvelopedSignature = `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"` +
+	` xmlns:wsse="` + wsseNS + `" xmlns:wsu="` + wsuNS + `">
+  <s:Header wsu:Id="Header-1"><wsse:Security>{signature}</wsse:Security></s:Header>
+  <s:Body wsu:Id="Body-1"><t:Data xmlns:t="urn:t">signed</t:Data></s:Body>
+</s:Envelope>`
+
+const testEnvelopedSignatureElement = `<ds:Signature xmlns:ds="` + dsNS + `"><ds:SignedInfo>` +
+	`<ds:CanonicalizationMethod Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"/>` +
+	`<ds:SignatureMethod Algorithm="http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"/>` +
+	`<ds:Reference URI="#Header-1"><ds:Transforms>` +
+	`<ds:Transform Algorithm="` + envelopedSigAlgo + `"/>` +
+	`<ds:Transform Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"/></ds:Transforms>` +
+	`<ds:DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"/>` +
+	`<ds:DigestValue>{header}</ds:DigestValue></ds:Reference>` +
+	`<ds:Reference URI="#Body-1"><ds:Transforms>` +
+	`<ds:Transform Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"/></ds:Transforms>` +
+	`<ds:DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"/>` +
+	`<ds:DigestValue>{body}</ds:DigestValue></ds:Reference>` +
+	`</ds:SignedInfo><ds:SignatureValue>{value}</ds:SignatureValue></ds:Signature>`
+
+// testCanonicalDigest returns the SHA-256 digest of the exclusive canonical form of the element named local
+// (or carrying the Id given as local) in raw
+func testCanonicalDigest(t *testing.T, raw string, local string) string {
+	t.Helper()
+	result, err := dom.CanonicalizeXML([]byte(raw), dom.ExclusiveC14N, nil, func(namespace string, name string, attributes []xml.Attr) bool {
+		if name == local {
+			return true
+		}
+		for _, attr := range attributes {
+			if attr.Name.Local == "Id" && attr.Value == local {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil || len(result) != 1 {
+		t.Fatalf("canonicalization of [%s] failed: %v", local, err)
+	}
+	digest := sha256.Sum256(result[0])
+	return base64.StdEncoding.EncodeToString(digest[:])
+}
+
+func TestSignatureEnvelopedTransform(t *testing.T) {
+	key := testKeys(t)["RSA"]
+	certificate := testCertificate(t, key)
+
+	// the digest of the header is computed without the signature it envelopes
+	unsigned := strings.Replace(testEnvelopedSignature, "{signature}", "", 1)
+	signature := strings.NewReplacer(
+		"{header}", testCanonicalDigest(t, unsigned, "Header-1"),
+		"{body}", testCanonicalDigest(t, unsigned, "Body-1"),
+	).Replace(testEnvelopedSignatureElement)
+	envelope := strings.Replace(testEnvelopedSignature, "{signature}", signature, 1)
+
+	canonical, err := dom.CanonicalizeXML([]byte(envelope), dom.ExclusiveC14N, nil, func(namespace string, name string, attributes []xml.Attr) bool {
+		return name == "SignedInfo"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := signDigest(key, canonical[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope = strings.Replace(envelope, "{value}", base64.StdEncoding.EncodeToString(value), 1)
+
+	response, err := ParseResponseE([]byte(envelope))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := response.VerifySignature(certificate); err != nil {
+		t.Fatal(err)
+	}
+
+	// the transform removes the signature only, not other content of the header
+	tampered, err := ParseResponseE([]byte(strings.Replace(envelope, "<wsse:Security>", "<wsse:Security><Injected/>", 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tampered.VerifySignature(certificate); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got [%v]", err)
+	}
+}
+
+func TestSignatureTimestamp(t *testing.T) {
+	keys := testKeys(t)
+	certificate := testCertificate(t, keys["RSA"])
+	client := newTestClient(t, testSchema(`elementFormDefault="qualified"`, testSignatureSchema),
+		WithX509Signature(certificate, keys["RSA"]))
+	defer func() { timeNow = time.Now }()
+
+	tests := []struct {
+		name   string
+		signed time.Duration
+		err    error
+	}{
+		{"current", 0, nil},
+		{"within lifetime", -4 * time.Minute, nil},
+		{"expired within clock skew", -signatureLifetime - 30*time.Second, nil},
+		{"expired", -signatureLifetime - 2*signatureClockSkew, ErrInvalidSignature},
+		{"future within clock skew", 30 * time.Second, nil},
+		{"future", 2 * signatureClockSkew, ErrInvalidSignature},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// sign at the given offset, verify now
+			timeNow = func() time.Time { return time.Now().Add(test.signed) }
+			request := newTestRequest(t, client)
+			request.SetBodyValues(dom.Convert("Request", dom.Map{"Name": "n"}))
+			signed, err := request.XMLE()
+			if err != nil {
+				t.Fatal(err)
+			}
+			timeNow = time.Now
+
+			response, err := ParseResponseE([]byte(signed))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := response.VerifySignature(certificate); !errors.Is(err, test.err) {
+				t.Fatalf("expected [%v], got [%v]", test.err, err)
+			}
+		})
+	}
+}
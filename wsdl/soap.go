@@ -0,0 +1,74 @@
+package wsdl
+
+import (
+	"fmt"
+
+	"github.com/lordkhonsu/go-soap/dom"
+)
+
+// SOAPVersion defines the SOAP protocol version used for a binding
+type SOAPVersion int
+
+const (
+	// SOAPVersionAuto detects the SOAP version from the WSDL binding
+	SOAPVersionAuto SOAPVersion = iota
+	// SOAP11 is SOAP 1.1 (soap:binding, text/xml, SOAPAction header)
+	SOAP11
+	// SOAP12 is SOAP 1.2 (soap12:binding, application/soap+xml with action parameter)
+	SOAP12
+)
+
+const (
+	soap11EnvelopeNS = "http://schemas.xmlsoap.org/soap/envelope/"
+	soap12EnvelopeNS = "http://www.w3.org/2003/05/soap-envelope"
+	soap11BindingNS  = "http://schemas.xmlsoap.org/wsdl/soap/"
+	soap12BindingNS  = "http://schemas.xmlsoap.org/wsdl/soap12/"
+	xsiNS            = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+// String returns the version as text
+func (v SOAPVersion) String() string {
+	switch v {
+	case SOAP11:
+		return "SOAP 1.1"
+	case SOAP12:
+		return "SOAP 1.2"
+	}
+	return "auto"
+}
+
+// EnvelopeNamespace returns the namespace of the <Envelope> for this version
+func (v SOAPVersion) EnvelopeNamespace() string {
+	if v == SOAP12 {
+		return soap12EnvelopeNS
+	}
+	return soap11EnvelopeNS
+}
+
+// ContentType returns the HTTP Content-Type for this version, SOAP 1.2 carries the action as parameter
+func (v SOAPVersion) ContentType(action string) string {
+	if v != SOAP12 {
+		return "text/xml; charset=utf-8"
+	}
+	if action == "" {
+		return "application/soap+xml; charset=utf-8"
+	}
+	return fmt.Sprintf("application/soap+xml; charset=utf-8; action=\"%s\"", action)
+}
+
+// bindingVersion detects the SOAP version by the namespace of the <binding> extensibility element
+// (SOAPVersionAuto if the binding is no SOAP binding, e.g. HTTP GET/POST)
+func bindingVersion(binding *dom.Node) SOAPVersion {
+	for _, child := range binding.XPath("binding").All() {
+		if child.Namespace == nil {
+			continue
+		}
+		switch child.Namespace.Name {
+		case soap11BindingNS:
+			return SOAP11
+		case soap12BindingNS:
+			return SOAP12
+		}
+	}
+	return SOAPVersionAuto
+}
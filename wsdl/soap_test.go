@@ -0,0 +1,99 @@
+package wsdl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lordkhonsu/go-soap/dom"
+)
+
+// testDefinitions12 returns testDefinitions with a SOAP 1.2 binding instead of the SOAP 1.1 one
+func testDefinitions12(schemas string) string {
+	return strings.NewReplacer(
+		`xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"`, `xmlns:soap12="http://schemas.xmlsoap.org/wsdl/soap12/"`,
+		"<soap:", "<soap12:",
+		"http://schemas.xmlsoap.org/soap/http", "http://www.w3.org/2003/05/soap/bindings/HTTP/",
+	).Replace(testDefinitions(schemas))
+}
+
+// testDefinitionsBoth returns testDefinitions offering the SOAP 1.2 binding as an additional port "TestPort12"
+func testDefinitionsBoth(schemas string) string {
+	return strings.NewReplacer(
+		`xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"`, `xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+  xmlns:soap12="http://schemas.xmlsoap.org/wsdl/soap12/"`,
+		`  <wsdl:service name="TestService">`, `  <wsdl:binding name="TestBinding12" type="tns:TestPort">
+    <soap12:binding transport="http://www.w3.org/2003/05/soap/bindings/HTTP/"/>
+    <wsdl:operation name="Call">
+      <soap12:operation soapAction="`+testNamespace+`/Call"/>
+      <wsdl:input><soap12:body use="literal"/></wsdl:input>
+      <wsdl:output><soap12:body use="literal"/></wsdl:output>
+    </wsdl:operation>
+  </wsdl:binding>
+  <wsdl:service name="TestService">
+    <wsdl:port name="TestPort12" binding="tns:TestBinding12">
+      <soap12:address location="http://localhost/test12"/>
+    </wsdl:port>`,
+	).Replace(testDefinitions(schemas))
+}
+
+func TestSOAPVersion(t *testing.T) {
+	schemas := testSchema("", `<xs:element name="Request" type="xs:string"/>`)
+	action := testNamespace + "/Call"
+
+	tests := []struct {
+		name        string
+		definitions string
+		options     []Option
+		version     SOAPVersion
+		url         string
+		contentType string
+		soapAction  string
+	}{
+		{"SOAP 1.1 binding", testDefinitions(schemas), nil,
+			SOAP11, "http://localhost/test", "text/xml; charset=utf-8", action},
+		{"SOAP 1.2 binding", testDefinitions12(schemas), nil,
+			SOAP12, "http://localhost/test", `application/soap+xml; charset=utf-8; action="` + action + `"`, ""},
+		{"both bindings", testDefinitionsBoth(schemas), nil,
+			SOAP12, "http://localhost/test12", `application/soap+xml; charset=utf-8; action="` + action + `"`, ""},
+		{"both bindings forcing SOAP 1.1", testDefinitionsBoth(schemas), []Option{WithSOAPVersion(SOAP11)},
+			SOAP11, "http://localhost/test", "text/xml; charset=utf-8", action},
+		{"SOAP 1.1 binding forcing SOAP 1.2", testDefinitions(schemas), []Option{WithSOAPVersion(SOAP12)},
+			SOAP12, "http://localhost/test", `application/soap+xml; charset=utf-8; action="` + action + `"`, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transport := respondWith(200, "application/soap+xml", "")
+			client, err := NewClientFromReader(strings.NewReader(test.definitions), append(test.options, WithTransport(transport))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := newTestRequest(t, client).SendE(); err != nil {
+				t.Fatal(err)
+			}
+
+			sent := transport.requests[0]
+			if sent.SOAPVersion != test.version || sent.URL != test.url {
+				t.Fatalf("expected %s to %s, got %s to %s", test.version, test.url, sent.SOAPVersion, sent.URL)
+			}
+			if contentType := sent.Header.Get("Content-Type"); contentType != test.contentType {
+				t.Fatalf("expected Content-Type [%s], got [%s]", test.contentType, contentType)
+			}
+			if soapAction, exists := sent.Header["Soapaction"]; test.soapAction == "" && exists ||
+				test.soapAction != "" && (len(soapAction) != 1 || soapAction[0] != test.soapAction) {
+				t.Fatalf("expected SOAPAction [%s], got %v", test.soapAction, soapAction)
+			}
+
+			envelope, err := dom.Parse(sent.Envelope)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if envelope.Root.Name != "Envelope" || envelope.Root.Namespace == nil ||
+				envelope.Root.Namespace.Name != test.version.EnvelopeNamespace() {
+				t.Fatalf("expected an envelope of %s, got %s", test.version.EnvelopeNamespace(), sent.Envelope)
+			}
+			if body := envelope.Root.XPathNS("s:Body", map[string]string{"s": test.version.EnvelopeNamespace()}).First(); body == nil {
+				t.Fatalf("expected the body in the envelope namespace, got %s", sent.Envelope)
+			}
+		})
+	}
+}
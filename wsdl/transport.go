@@ -0,0 +1,64 @@
+package wsdl
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+)
+
+// TransportRequest is the outgoing SOAP message handed to a Transport
+type TransportRequest struct {
+	URL         string
+	Action      string
+	SOAPVersion SOAPVersion
+	// Header holds the HTTP headers (Content-Type, SOAPAction, default headers); non-HTTP transports may ignore them
+	Header   http.Header
+	Envelope []byte
+}
+
+// TransportResponse is the raw answer received by a Transport
+type TransportResponse struct {
+	// StatusCode is the HTTP status, non-HTTP transports may leave it 0
+	StatusCode int
+	Header     http.Header
+	Envelope   []byte
+}
+
+// Transport delivers a SOAP envelope to its endpoint and returns the answer, see WithTransport
+type Transport interface {
+	RoundTrip(ctx context.Context, request *TransportRequest) (*TransportResponse, error)
+}
+
+// HTTPTransport is the default Transport, posting the envelope with a http.Client
+type HTTPTransport struct {
+	Client *http.Client
+}
+
+// RoundTrip implements the Transport interface
+func (t *HTTPTransport) RoundTrip(ctx context.Context, request *TransportRequest) (*TransportResponse, error) {
+	httpRequest, err := http.NewRequestWithContext(ctx, "POST", request.URL, bytes.NewBuffer(request.Envelope))
+	if err != nil {
+		return nil, &TransportError{URL: request.URL, Err: err}
+	}
+	for name, values := range request.Header {
+		httpRequest.Header[name] = values
+	}
+
+	httpResponse, err := t.Client.Do(httpRequest)
+	if err != nil {
+		return nil, &TransportError{URL: request.URL, Err: contextError(ctx, err)}
+	}
+	defer httpResponse.Body.Close()
+
+	raw, err := ioutil.ReadAll(httpResponse.Body)
+	if err != nil {
+		return nil, &TransportError{URL: request.URL, StatusCode: httpResponse.StatusCode, Err: contextError(ctx, err)}
+	}
+
+	return &TransportResponse{
+		StatusCode: httpResponse.StatusCode,
+		Header:     httpResponse.Header,
+		Envelope:   raw,
+	}, nil
+}
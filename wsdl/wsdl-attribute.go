@@ -0,0 +1,111 @@
+package wsdl
+
+import (
+	"fmt"
+
+	"github.com/lordkhonsu/go-soap/dom"
+)
+
+// buildAttributes sets the <attribute>s declared by content (a complexType, its derivation or an <attributeGroup>) on self,
+// taking the values from the attributes of the body value node. Attributes in declared (keyed by {namespace}name) were
+// declared by a derived type already
+func (w *WSDL) buildAttributes(content *dom.Node, targetNamespace string, self *dom.Node, values *dom.Node, declared map[string]bool) error {
+	for _, declaration := range content.Children.All() {
+		switch declaration.Name {
+		case "attribute":
+			if err := w.buildAttribute(declaration, targetNamespace, self, values, declared); err != nil {
+				return err
+			}
+
+		case "attributeGroup":
+			group, namespace, err := w.lookupComponent("attributeGroup", declaration.GetAttributeValue("ref"), declaration)
+			if err != nil {
+				return err
+			}
+			if err := w.buildAttributes(group, namespace, self, values, declared); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// buildAttribute sets a single <attribute> on self; absent values fall back to fixed and default
+func (w *WSDL) buildAttribute(declaration *dom.Node, targetNamespace string, self *dom.Node, values *dom.Node, declared map[string]bool) error {
+	// global attributes are always qualified, local ones depend on form
+	attribute, namespace := declaration, ""
+	if ref := declaration.GetAttributeValue("ref"); ref != "" {
+		var err error
+		if attribute, namespace, err = w.lookupComponent("attribute", ref, declaration); err != nil {
+			return err
+		}
+	} else if isQualified(declaration, "attributeFormDefault") {
+		namespace = targetNamespace
+	}
+	name := attribute.GetAttributeValue("name")
+
+	// a restriction redeclares or prohibits attributes of its base
+	key := "{" + namespace + "}" + name
+	if declared[key] {
+		return nil
+	}
+	declared[key] = true
+	use := declaration.GetAttributeValue("use")
+	if use == "prohibited" {
+		return nil
+	}
+
+	// fixed and default may be given by the reference or the referenced attribute
+	fixed, hasFixed := attributeConstraint(declaration, attribute, "fixed")
+	def, hasDefault := attributeConstraint(declaration, attribute, "default")
+
+	given, exists := attributeValue(values, namespace, name)
+	switch {
+	case exists && hasFixed && given.Value != fixed:
+		return fmt.Errorf("%w: attribute [%s] of [%s] is fixed to [%s], got [%s]", ErrInvalidBody, name,
+			self.GetXPath(), fixed, given.Value)
+	case exists:
+		self.SetAttribute(namespace, name, given.Value)
+	case hasFixed:
+		self.SetAttribute(namespace, name, fixed)
+	case hasDefault:
+		self.SetAttribute(namespace, name, def)
+	case use == "required":
+		return fmt.Errorf("%w: required attribute [%s] missing in [%s]", ErrInvalidBody, name, self.GetXPath())
+	}
+	return nil
+}
+
+// attributeValue returns the attribute of the body value node in namespace, qualified attributes may also be given
+// without namespace
+func attributeValue(values *dom.Node, namespace string, name string) (*dom.Attribute, bool) {
+	if given, exists := values.GetAttributeNS(namespace, name); exists || namespace == "" {
+		return given, exists
+	}
+	return values.GetAttributeNS("", name)
+}
+
+// attributeConstraint returns the fixed or default value (kind) of an attribute declaration or its reference
+func attributeConstraint(declaration *dom.Node, attribute *dom.Node, kind string) (string, bool) {
+	if constraint, exists := declaration.GetAttribute(kind); exists {
+		return constraint.Value, true
+	}
+	if constraint, exists := attribute.GetAttribute(kind); exists {
+		return constraint.Value, true
+	}
+	return "", false
+}
+
+// isQualified returns true if the local declaration belongs to the target namespace, by its form or the
+// formDefault (elementFormDefault or attributeFormDefault) of its <schema>
+func isQualified(declaration *dom.Node, formDefault string) bool {
+	if form := declaration.GetAttributeValue("form"); form != "" {
+		return form == "qualified"
+	}
+	for node := declaration.Parent; node != nil; node = node.Parent {
+		if node.Name == "schema" {
+			return node.GetAttributeValue(formDefault) == "qualified"
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package wsdl
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lordkhonsu/go-soap/dom"
+)
+
+const testOtherNamespace = "urn:other"
+
+const testAttributeSchemas = `<xs:schema targetNamespace="` + testOtherNamespace + `">
+    <xs:attribute name="code" type="xs:string"/>
+  </xs:schema>`
+
+const testAttributes = `<xs:attributeGroup name="Audit">
+      <xs:attribute name="user" type="xs:string" default="anonymous"/>
+    </xs:attributeGroup>
+    <xs:complexType name="Base">
+      <xs:attribute name="id" type="xs:string" use="required"/>
+      <xs:attribute name="note" type="xs:string"/>
+    </xs:complexType>
+    <xs:complexType name="Restricted"><xs:complexContent><xs:restriction base="tns:Base">
+      <xs:attribute name="note" type="xs:string" use="prohibited"/>
+    </xs:restriction></xs:complexContent></xs:complexType>
+    <xs:element name="Request"><xs:complexType><xs:complexContent><xs:extension base="tns:Restricted">
+      <xs:attribute name="version" type="xs:string" fixed="1"/>
+      <xs:attribute name="lang" type="xs:string" default="en"/>
+      <xs:attribute name="scope" type="xs:string" form="qualified"/>
+      <xs:attribute ref="o:code"/>
+      <xs:attribute name="code" type="xs:string"/>
+      <xs:attributeGroup ref="tns:Audit"/>
+    </xs:extension></xs:complexContent></xs:complexType></xs:element>`
+
+// attributeValues returns the body values of tns:Request with the attributes given as namespace, name, value triples
+func attributeValues(triples ...string) *dom.Document {
+	values := dom.NewDocument("Request")
+	for i := 0; i+2 < len(triples); i += 3 {
+		values.Root.SetAttribute(triples[i], triples[i+1], triples[i+2])
+	}
+	return values
+}
+
+// describeAttributes lists the attributes built into the request element as {namespace}name=value, except xsi:*
+func describeAttributes(envelope *dom.Document) string {
+	result := []string{}
+	for _, attr := range envelope.Root.XPath("Body/Request").First().Attributes {
+		if attr.InNamespace(xsiNS) {
+			continue
+		}
+		name := attr.Name
+		if attr.Namespace != nil {
+			name = "{" + attr.Namespace.Name + "}" + name
+		}
+		result = append(result, name+"="+attr.Value)
+	}
+	return strings.Join(result, " ")
+}
+
+func TestBuildAttributes(t *testing.T) {
+	client := newTestClient(t, testAttributeSchemas+testSchema(`xmlns:tns="`+testNamespace+`" xmlns:o="`+testOtherNamespace+`"`,
+		testAttributes))
+
+	tests := []struct {
+		name     string
+		values   *dom.Document
+		expected string
+		err      error
+	}{
+		{"fixed and default", attributeValues("", "id", "1"), "version=1 lang=en user=anonymous id=1", nil},
+		{"given", attributeValues("", "id", "1", "", "lang", "de", "", "user", "u", "", "version", "1"),
+			"version=1 lang=de user=u id=1", nil},
+		{"fixed mismatch", attributeValues("", "id", "1", "", "version", "2"), "", ErrInvalidBody},
+		{"required missing", attributeValues(), "", ErrInvalidBody},
+		{"prohibited by restriction", attributeValues("", "id", "1", "", "note", "n"), "version=1 lang=en user=anonymous id=1", nil},
+		{"qualified given unqualified", attributeValues("", "id", "1", "", "scope", "s"),
+			"version=1 lang=en {" + testNamespace + "}scope=s user=anonymous id=1", nil},
+		{"same local name in different namespaces", attributeValues("", "id", "1", testOtherNamespace, "code", "a", "", "code", "b"),
+			"version=1 lang=en {" + testOtherNamespace + "}code=a code=b user=anonymous id=1", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			envelope, err := buildTestBody(t, client, test.values, nil)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("expected [%v], got [%v]", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result := describeAttributes(envelope); result != test.expected {
+				t.Fatalf("expected [%s], got [%s]", test.expected, result)
+			}
+		})
+	}
+}
@@ -11,29 +11,33 @@ type Element struct {
 	wsdl            *WSDL
 	targetNamespace string
 	domNode         *dom.Node
+
+	// local <element ref=> using this global element, it defines the occurrence
+	reference *dom.Node
 }
 
-func (e *Element) resolveType() *Type {
+func (e *Element) resolveType() (*Type, error) {
 	if typeName := e.domNode.GetAttributeValue("type"); len(typeName) > 0 {
-		return e.wsdl.FindType(typeName, e.domNode)
+		return e.wsdl.LookupType(typeName, e.domNode)
 
 	} else if embedded := e.domNode.XPath("complexType").First(); embedded.Exists {
 		return &Type{
 			wsdl:            e.wsdl,
 			targetNamespace: e.targetNamespace,
 			domNode:         embedded,
-		}
+		}, nil
 
 	} else if embedded := e.domNode.XPath("simpleType").First(); embedded.Exists {
 		return &Type{
 			wsdl:            e.wsdl,
 			targetNamespace: e.targetNamespace,
 			domNode:         embedded,
-		}
+		}, nil
 
 	}
 
-	panic(fmt.Errorf("resolveType(): type for element [%s] could not be resolved", e.Name()))
+	return nil, &ResolveError{Kind: ErrUnresolvedType, FQName: e.Name(), Namespace: e.targetNamespace,
+		Err: fmt.Errorf("resolveType(): element declares no type")}
 }
 
 // Name returns the Name for this <element>
@@ -41,18 +45,48 @@ func (e *Element) Name() string {
 	return e.domNode.GetAttributeValue("name")
 }
 
+// Namespace returns the namespace this <element> is written in: global elements are always qualified,
+// local ones by their form or the elementFormDefault of their <schema>
+func (e *Element) Namespace() string {
+	if e.isGlobal() || isQualified(e.domNode, "elementFormDefault") {
+		return e.targetNamespace
+	}
+	return ""
+}
+
+// isGlobal returns true for top-level elements of a <schema>
+func (e *Element) isGlobal() bool {
+	return e.domNode.Parent != nil && e.domNode.Parent.Name == "schema"
+}
+
 // MinOccurs returns the minimum amount this element must appear
 func (e *Element) MinOccurs() int {
-	if v := e.domNode.GetAttributeValue("minOccurs"); len(v) > 0 {
+	if e.reference != nil {
+		return minOccurs(e.reference)
+	}
+	return minOccurs(e.domNode)
+}
+
+// MaxOccurs returns the maximum amount this element may appear (-1 = unlimited)
+func (e *Element) MaxOccurs() int {
+	if e.reference != nil {
+		return maxOccurs(e.reference)
+	}
+	return maxOccurs(e.domNode)
+}
+
+// minOccurs returns the minOccurs of an element or model group
+func minOccurs(node *dom.Node) int {
+	if v := node.GetAttributeValue("minOccurs"); len(v) > 0 {
 		buffer, _ := strconv.ParseInt(v, 10, 64)
 		return int(buffer)
 	}
 	return 1
 }
 
-// MaxOccurs returns the maximum amount this element may appear (-1 = unlimited)
-func (e *Element) MaxOccurs() int {
-	if v := e.domNode.GetAttributeValue("maxOccurs"); len(v) > 0 {
+// maxOccurs returns the maxOccurs of an element or model group (-1 = unlimited)
+func maxOccurs(node *dom.Node) int {
+	if v := node.GetAttributeValue("maxOccurs"); len(v) > 0 {
 		if v == "unbounded" {
 			return -1
 		}
@@ -72,33 +106,46 @@ func (e *Element) IsNillable() bool {
 
 // Build builds this <element> and attaches it to the given parent dom.Node
 func (e *Element) Build(parent *dom.Node, body *dom.Document, typeExtensions map[string]string) {
-	myType := e.resolveType()
+	if err := e.BuildE(parent, body, typeExtensions); err != nil {
+		panic(err)
+	}
+}
 
-	myXPath := parent.GetXPath() + "/" + parent.GetXPathName(e.Name())
-	if extension, exists := typeExtensions[myXPath]; exists {
-		myType = e.wsdl.FindType(extension, e.domNode)
+// BuildE builds this <element> and attaches it to the given parent dom.Node, returning an error instead of panicking
+func (e *Element) BuildE(parent *dom.Node, body *dom.Document, typeExtensions map[string]string) error {
+	return e.build(parent, valuesOf(body, parent), typeExtensions)
+}
+
+// build builds the occurrences of this <element> into parent, taking them from values (the body values of parent)
+func (e *Element) build(parent *dom.Node, values *dom.Node, typeExtensions map[string]string) error {
+	myType, err := e.resolveType()
+	if err != nil {
+		return err
 	}
 
-	// count values
-	values := body.XPath(parent.GetXPath() + "/" + e.Name()).Len()
+	// values not yet used by a previous occurrence of the surrounding model group, which may have run out of them
+	given := namedChildren(values, e.Name())
+	given = given[min(e.builtOccurrences(parent), len(given)):]
 	count := 0
 	minOccurs := e.MinOccurs()
 	maxOccurs := e.MaxOccurs()
 
 	/*
 		fmt.Printf("name = %s, min = %d, max = %d, values = %d, nillable = %v\n",
-			e.Name(), minOccurs, maxOccurs, values, e.IsNillable())
+			e.Name(), minOccurs, maxOccurs, len(given), e.IsNillable())
 	*/
 
-	if minOccurs == 0 && values == 0 {
+	if minOccurs == 0 && len(given) == 0 {
 		// skip if we may
-		return
+		return nil
 	}
 
 	for {
-		myType.build(parent, e.Name(), e.targetNamespace, body, typeExtensions)
+		if err := e.buildOccurrence(myType, parent, occurrenceValues(given, count), typeExtensions); err != nil {
+			return err
+		}
 		count++
-		if (maxOccurs >= 0 && count >= maxOccurs) || (count >= minOccurs && values <= count) {
+		if (maxOccurs >= 0 && count >= maxOccurs) || (count >= minOccurs && len(given) <= count) {
 			// we have reached our end, stop here
 			break
 		}
@@ -108,7 +155,121 @@ func (e *Element) Build(parent *dom.Node, body *dom.Document, typeExtensions map
 	if missing > 0 {
 		for i := 0; i < missing; i++ {
 			// we are missing some, fill
-			myType.build(parent, e.Name(), e.targetNamespace, body, typeExtensions)
+			if err := e.buildOccurrence(myType, parent, &dom.Node{}, typeExtensions); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// buildOccurrence builds the next occurrence of this <element> from its values, using the type extension
+// registered for its XPath
+func (e *Element) buildOccurrence(myType *Type, parent *dom.Node, values *dom.Node, typeExtensions map[string]string) error {
+	// positional XPaths are expensive to build, only do so if needed
+	if len(typeExtensions) > 0 {
+		myXPath := parent.GetXPath() + "/" + parent.GetXPathName(e.Name())
+		if extension, exists := typeExtensions[myXPath]; exists {
+			declared := myType
+			var err error
+			if myType, err = e.wsdl.LookupType(extension, e.domNode); err != nil {
+				return err
+			}
+			if err := e.checkSubstitution(declared, myType, myXPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	if myType.IsAbstract() {
+		return fmt.Errorf("%w: type [%s] of [%s] is abstract, set a derived type with SetTypeExtension", ErrInvalidBody,
+			myType.Name(), parent.GetXPath()+"/"+parent.GetXPathName(e.Name()))
+	}
+
+	_, err := myType.build(parent, e.Name(), e.Namespace(), values, typeExtensions)
+	return err
+}
+
+// remainingValues returns the number of values for this <element> that have not been built into parent yet
+func (e *Element) remainingValues(parent *dom.Node, values *dom.Node) int {
+	return len(namedChildren(values, e.Name())) - e.builtOccurrences(parent)
+}
+
+// builtOccurrences returns the number of occurrences of this <element> built into parent so far
+func (e *Element) builtOccurrences(parent *dom.Node) int {
+	built := 0
+	for _, child := range parent.Children.All() {
+		if child.Name == e.Name() {
+			built++
+		}
+	}
+	return built
+}
+
+// valuesOf returns the node of the body values for a node of the envelope being built, matching names and positions
+// just like its XPath would
+func valuesOf(body *dom.Document, node *dom.Node) *dom.Node {
+	if node.Parent == nil {
+		if body.Root != nil && localName(body.Root.Name) == localName(node.Name) {
+			return body.Root
+		}
+		return &dom.Node{}
+	}
+
+	index := 0
+	for _, sibling := range node.Parent.Children.All() {
+		if sibling == node {
+			break
+		}
+		if sibling.Name == node.Name {
+			index++
+		}
+	}
+	return occurrenceValues(namedChildren(valuesOf(body, node.Parent), node.Name), index)
+}
+
+// namedChildren returns the children of values with the given (local) name
+func namedChildren(values *dom.Node, name string) []*dom.Node {
+	result := []*dom.Node{}
+	for _, child := range values.Children.All() {
+		if localName(child.Name) == name {
+			result = append(result, child)
+		}
+	}
+	return result
+}
+
+// occurrenceValues returns the values of the index-th occurrence, a non-existing node if there are none
+func occurrenceValues(given []*dom.Node, index int) *dom.Node {
+	if index < len(given) {
+		return given[index]
+	}
+	return &dom.Node{}
+}
+
+func localName(name string) string {
+	_, local := dom.SplitFQName(name)
+	return local
+}
+
+// checkSubstitution checks that derived may replace the declared type of this <element> as xsi:type
+func (e *Element) checkSubstitution(declared *Type, derived *Type, xpath string) error {
+	methods, isDerived, err := derived.derivationMethods(declared)
+	if err != nil {
+		return err
+	}
+	if !isDerived {
+		return fmt.Errorf("%w: type [%s] for [%s] is not derived from [%s]", ErrInvalidBody, derived.Name(), xpath,
+			declared.Name())
+	}
+
+	elementBlock := derivationControl(e.domNode, "block", "blockDefault")
+	typeBlock := declared.derivationControl("block", "blockDefault")
+	for _, method := range methods {
+		if elementBlock[method] || typeBlock[method] {
+			return fmt.Errorf("%w: [%s] blocks types derived by %s like [%s]", ErrInvalidBody, xpath, method,
+				derived.Name())
 		}
 	}
+	return nil
 }
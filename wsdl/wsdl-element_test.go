@@ -0,0 +1,97 @@
+package wsdl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lordkhonsu/go-soap/dom"
+)
+
+// testFormSchemas declares a global element of another namespace, whose local elements and attributes are unqualified
+const testFormSchemas = `<xs:schema targetNamespace="` + testOtherNamespace + `">
+    <xs:element name="Global"><xs:complexType><xs:sequence>
+      <xs:element name="Child" type="xs:string"/>
+    </xs:sequence><xs:attribute name="flag" type="xs:string"/></xs:complexType></xs:element>
+  </xs:schema>`
+
+const testForms = `<xs:element name="Request"><xs:complexType><xs:sequence>
+      <xs:element name="Local" type="xs:string"/>
+      <xs:element name="Qualified" type="xs:string" form="qualified"/>
+      <xs:element name="Unqualified" form="unqualified"><xs:complexType><xs:sequence>
+        <xs:element name="Nested" type="xs:string"/>
+      </xs:sequence></xs:complexType></xs:element>
+      <xs:element ref="o:Global"/>
+    </xs:sequence>
+    <xs:attribute name="local" type="xs:string"/>
+    <xs:attribute name="qualified" type="xs:string" form="qualified"/>
+    <xs:attribute name="unqualified" type="xs:string" form="unqualified"/>
+  </xs:complexType></xs:element>`
+
+// qualifiedName returns the name of an element or attribute as {namespace}name, or name without namespace
+func qualifiedName(name string, namespace *dom.Namespace) string {
+	if namespace == nil || namespace.Name == "" {
+		return name
+	}
+	return "{" + namespace.Name + "}" + name
+}
+
+// describeNamespaces lists the elements and attributes (except xsi:*) of the request element and its descendants
+// as {namespace}name, attributes prefixed by @
+func describeNamespaces(node *dom.Node) []string {
+	result := []string{qualifiedName(node.Name, node.Namespace)}
+	for _, attr := range node.Attributes {
+		if !attr.InNamespace(xsiNS) {
+			result = append(result, "@"+qualifiedName(attr.Name, attr.Namespace))
+		}
+	}
+	for _, child := range node.Children.All() {
+		result = append(result, describeNamespaces(child)...)
+	}
+	return result
+}
+
+func TestBuildNamespaces(t *testing.T) {
+	tns, other := "{"+testNamespace+"}", "{"+testOtherNamespace+"}"
+	values := dom.Map{
+		"@local": "l", "@qualified": "q", "@unqualified": "u",
+		"Local": "l", "Qualified": "q", "Unqualified": dom.Map{"Nested": "n"},
+		"Global": dom.Map{"@flag": "f", "Child": "c"},
+	}
+
+	tests := []struct {
+		name       string
+		attributes string
+		expected   []string
+	}{
+		{"unqualified by default", "", []string{
+			tns + "Request", "@local", "@" + tns + "qualified", "@unqualified",
+			"Local", tns + "Qualified", "Unqualified", "Nested",
+			other + "Global", "@flag", "Child"}},
+		{"qualified elements", `elementFormDefault="qualified"`, []string{
+			tns + "Request", "@local", "@" + tns + "qualified", "@unqualified",
+			tns + "Local", tns + "Qualified", "Unqualified", tns + "Nested",
+			other + "Global", "@flag", "Child"}},
+		{"qualified attributes", `attributeFormDefault="qualified"`, []string{
+			tns + "Request", "@" + tns + "local", "@" + tns + "qualified", "@unqualified",
+			"Local", tns + "Qualified", "Unqualified", "Nested",
+			other + "Global", "@flag", "Child"}},
+		{"qualified elements and attributes", `elementFormDefault="qualified" attributeFormDefault="qualified"`, []string{
+			tns + "Request", "@" + tns + "local", "@" + tns + "qualified", "@unqualified",
+			tns + "Local", tns + "Qualified", "Unqualified", tns + "Nested",
+			other + "Global", "@flag", "Child"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := newTestClient(t, testFormSchemas+testSchema(`xmlns:tns="`+testNamespace+`" xmlns:o="`+testOtherNamespace+`" `+
+				test.attributes, testForms))
+			envelope, err := buildTestBody(t, client, dom.Convert("Request", values), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := describeNamespaces(envelope.Root.XPath("Body/*").First())
+			if strings.Join(result, " ") != strings.Join(test.expected, " ") {
+				t.Fatalf("expected\n%s\ngot\n%s", strings.Join(test.expected, " "), strings.Join(result, " "))
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package wsdl
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lordkhonsu/go-soap/dom"
+)
+
+// isModelGroup returns true for the model groups a complexType may contain: <sequence>, <choice>, <all> or a <group> reference
+func isModelGroup(node *dom.Node) bool {
+	switch node.Name {
+	case "sequence", "choice", "all", "group":
+		return true
+	}
+	return false
+}
+
+// buildParticle builds an <element> or model group of a content model into parent; other particles like <any> are skipped
+func (w *WSDL) buildParticle(particle *dom.Node, targetNamespace string, parent *dom.Node, values *dom.Node, typeExtensions map[string]string) error {
+	switch particle.Name {
+	case "element":
+		element, err := w.particleElement(particle, targetNamespace)
+		if err != nil {
+			return err
+		}
+		return element.build(parent, values, typeExtensions)
+
+	case "sequence", "choice", "all":
+		return w.buildModelGroup(particle, particle, targetNamespace, parent, values, typeExtensions)
+
+	case "group":
+		group, namespace, err := w.resolveGroupRef(particle)
+		if err != nil {
+			return err
+		}
+		// the occurrence of a <group> reference is defined by the reference, not by the group
+		return w.buildModelGroup(group, particle, namespace, parent, values, typeExtensions)
+	}
+	return nil
+}
+
+// particleElement returns the <element> of a content model, resolving references to global elements
+func (w *WSDL) particleElement(particle *dom.Node, targetNamespace string) (*Element, error) {
+	if ref := particle.GetAttributeValue("ref"); ref != "" {
+		element, err := w.LookupElement(ref, particle)
+		if err != nil {
+			return nil, err
+		}
+		element.reference = particle
+		return element, nil
+	}
+	return &Element{
+		wsdl:            w,
+		targetNamespace: targetNamespace,
+		domNode:         particle,
+	}, nil
+}
+
+// resolveGroupRef returns the <sequence>, <choice> or <all> of the <group> referenced by particle
+func (w *WSDL) resolveGroupRef(particle *dom.Node) (*dom.Node, string, error) {
+	groupNode, namespace, err := w.lookupComponent("group", particle.GetAttributeValue("ref"), particle)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, child := range groupNode.Children.All() {
+		if child.Name == "sequence" || child.Name == "choice" || child.Name == "all" {
+			return child, namespace, nil
+		}
+	}
+	return nil, "", &ResolveError{Kind: ErrUnresolvedGroup, FQName: particle.GetAttributeValue("ref"), Namespace: namespace,
+		Err: fmt.Errorf("resolveGroupRef(): group declares no model group")}
+}
+
+// buildModelGroup builds the model group as often as occurs (the group or its reference) allows and values are left
+func (w *WSDL) buildModelGroup(group *dom.Node, occurs *dom.Node, targetNamespace string, parent *dom.Node, values *dom.Node, typeExtensions map[string]string) error {
+	minOccurs := minOccurs(occurs)
+	maxOccurs := maxOccurs(occurs)
+
+	for count := 0; maxOccurs < 0 || count < maxOccurs; count++ {
+		if count >= minOccurs && !w.hasValues(group, parent, values) {
+			break
+		}
+
+		built := parent.Children.Len()
+		if group.Name == "choice" {
+			if err := w.buildChoice(group, maxOccurs != 1, targetNamespace, parent, values, typeExtensions); err != nil {
+				return err
+			}
+		} else {
+			// <sequence> and <all> are built in the declared order
+			for _, particle := range group.Children.All() {
+				if err := w.buildParticle(particle, targetNamespace, parent, values, typeExtensions); err != nil {
+					return err
+				}
+			}
+		}
+
+		if count >= minOccurs && parent.Children.Len() == built {
+			// no progress, the remaining values do not fit
+			break
+		}
+	}
+	return nil
+}
+
+// buildChoice builds the branch of the <choice> the values were given for; a repeated choice takes the first
+// branch with values left in every occurrence
+func (w *WSDL) buildChoice(choice *dom.Node, repeated bool, targetNamespace string, parent *dom.Node, values *dom.Node, typeExtensions map[string]string) error {
+	branches := []*dom.Node{}
+	names := []string{}
+	for _, particle := range choice.Children.All() {
+		if (particle.Name == "element" || isModelGroup(particle)) && w.hasValues(particle, parent, values) {
+			branches = append(branches, particle)
+			names = append(names, particleName(particle))
+		}
+	}
+
+	switch {
+	case len(branches) == 0:
+		return fmt.Errorf("%w: no branch of choice given in [%s]", ErrInvalidBody, parent.GetXPath())
+	case len(branches) > 1 && !repeated:
+		return fmt.Errorf("%w: several branches of choice given in [%s]: %s", ErrInvalidBody, parent.GetXPath(),
+			strings.Join(names, ", "))
+	}
+	return w.buildParticle(branches[0], targetNamespace, parent, values, typeExtensions)
+}
+
+// hasValues returns true if values (the body values of parent) hold values for particle not built into parent yet
+func (w *WSDL) hasValues(particle *dom.Node, parent *dom.Node, values *dom.Node) bool {
+	switch particle.Name {
+	case "element":
+		if element, err := w.particleElement(particle, ""); err == nil {
+			return element.remainingValues(parent, values) > 0
+		}
+
+	case "sequence", "choice", "all":
+		for _, child := range particle.Children.All() {
+			if w.hasValues(child, parent, values) {
+				return true
+			}
+		}
+
+	case "group":
+		if group, _, err := w.resolveGroupRef(particle); err == nil {
+			return w.hasValues(group, parent, values)
+		}
+	}
+	return false
+}
+
+// particleName describes a particle for error messages
+func particleName(particle *dom.Node) string {
+	switch particle.Name {
+	case "element":
+		if ref := particle.GetAttributeValue("ref"); ref != "" {
+			_, name := dom.SplitFQName(ref)
+			return name
+		}
+		return particle.GetAttributeValue("name")
+	case "group":
+		return "group " + particle.GetAttributeValue("ref")
+	}
+	names := []string{}
+	for _, child := range particle.Children.All() {
+		if child.Name == "element" || isModelGroup(child) {
+			names = append(names, particleName(child))
+		}
+	}
+	return particle.Name + "(" + strings.Join(names, ", ") + ")"
+}
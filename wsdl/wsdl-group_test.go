@@ -0,0 +1,131 @@
+package wsdl
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lordkhonsu/go-soap/dom"
+)
+
+// orderedValues returns the body values of tns:Request with children given as name, value pairs in this order
+func orderedValues(pairs ...string) *dom.Document {
+	values := dom.NewDocument("Request")
+	for i := 0; i+1 < len(pairs); i += 2 {
+		values.Root.NewChildren(pairs[i], "").SetValue(pairs[i+1])
+	}
+	return values
+}
+
+// describeRequest lists the children built into the request element as name=value
+func describeRequest(envelope *dom.Document) string {
+	result := []string{}
+	for _, child := range envelope.Root.XPath("Body/Request/*").All() {
+		result = append(result, child.Name+"="+child.String())
+	}
+	return strings.Join(result, " ")
+}
+
+type builderTest struct {
+	name         string
+	declarations string
+	values       *dom.Document
+	expected     string
+	err          error
+}
+
+func runBuilderTests(t *testing.T, tests []builderTest) {
+	t.Helper()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := newTestClient(t, testSchema(`xmlns:tns="`+testNamespace+`"`, test.declarations))
+			envelope, err := buildTestBody(t, client, test.values, nil)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("expected [%v], got [%v]", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result := describeRequest(envelope); result != test.expected {
+				t.Fatalf("expected [%s], got [%s]", test.expected, result)
+			}
+		})
+	}
+}
+
+const (
+	testChoice = `<xs:element name="Request"><xs:complexType><xs:choice>
+      <xs:element name="Id" type="xs:int"/>
+      <xs:element name="Name" type="xs:string"/>
+    </xs:choice></xs:complexType></xs:element>`
+	testOptionalChoice = `<xs:element name="Request"><xs:complexType><xs:sequence>
+      <xs:element name="First" type="xs:string"/>
+      <xs:choice minOccurs="0"><xs:element name="Id" type="xs:int"/><xs:element name="Name" type="xs:string"/></xs:choice>
+    </xs:sequence></xs:complexType></xs:element>`
+	testRepeatedChoice = `<xs:element name="Request"><xs:complexType><xs:choice maxOccurs="unbounded">
+      <xs:element name="Tag" type="xs:string"/>
+      <xs:element name="Label" type="xs:string"/>
+    </xs:choice></xs:complexType></xs:element>`
+	testNestedChoice = `<xs:element name="Request"><xs:complexType><xs:choice>
+      <xs:sequence><xs:element name="User" type="xs:string"/><xs:element name="Password" type="xs:string"/></xs:sequence>
+      <xs:element name="Token" type="xs:string"/>
+    </xs:choice></xs:complexType></xs:element>`
+	testAll = `<xs:element name="Request"><xs:complexType><xs:all>
+      <xs:element name="A" type="xs:string"/>
+      <xs:element name="B" type="xs:string" minOccurs="0"/>
+      <xs:element name="C" type="xs:string"/>
+    </xs:all></xs:complexType></xs:element>`
+	testGroup = `<xs:group name="Contact"><xs:sequence>
+      <xs:element name="Phone" type="xs:string"/>
+      <xs:element name="Mail" type="xs:string" minOccurs="0"/>
+    </xs:sequence></xs:group>
+    <xs:element name="Request"><xs:complexType><xs:sequence>
+      <xs:element name="Name" type="xs:string"/>
+      <xs:group ref="tns:Contact" minOccurs="0" maxOccurs="2"/>
+    </xs:sequence></xs:complexType></xs:element>`
+	testRepeatedSequence = `<xs:element name="Request"><xs:complexType><xs:sequence maxOccurs="unbounded">
+      <xs:element name="Key" type="xs:string"/>
+      <xs:element name="Value" type="xs:string"/>
+    </xs:sequence></xs:complexType></xs:element>`
+	testRequiredSequence = `<xs:element name="Request"><xs:complexType><xs:sequence minOccurs="2" maxOccurs="unbounded">
+      <xs:element name="A" type="xs:string"/>
+      <xs:element name="B" type="xs:string"/>
+    </xs:sequence></xs:complexType></xs:element>`
+	testUnresolvedGroup = `<xs:element name="Request"><xs:complexType><xs:sequence>
+      <xs:group ref="tns:Missing"/>
+    </xs:sequence></xs:complexType></xs:element>`
+)
+
+func TestBuildModelGroups(t *testing.T) {
+	runBuilderTests(t, []builderTest{
+		{name: "choice", declarations: testChoice, values: orderedValues("Name", "n"), expected: "Name=n"},
+		{name: "choice without branch", declarations: testChoice, values: orderedValues(), err: ErrInvalidBody},
+		{name: "choice with several branches", declarations: testChoice, values: orderedValues("Id", "1", "Name", "n"), err: ErrInvalidBody},
+		{name: "optional choice", declarations: testOptionalChoice, values: orderedValues("First", "f"), expected: "First=f"},
+		{name: "optional choice given", declarations: testOptionalChoice, values: orderedValues("First", "f", "Id", "1"), expected: "First=f Id=1"},
+		{name: "repeated choice", declarations: testRepeatedChoice, values: orderedValues("Tag", "a", "Tag", "b", "Label", "c"),
+			expected: "Tag=a Tag=b Label=c"},
+		{name: "nested sequence branch", declarations: testNestedChoice, values: orderedValues("User", "u", "Password", "p"),
+			expected: "User=u Password=p"},
+		{name: "nested element branch", declarations: testNestedChoice, values: orderedValues("Token", "t"), expected: "Token=t"},
+		{name: "all in declared order", declarations: testAll, values: orderedValues("C", "3", "A", "1"), expected: "A=1 C=3"},
+		{name: "all with missing element", declarations: testAll, values: orderedValues("B", "2"), expected: "A= B=2 C="},
+		{name: "group reference", declarations: testGroup, values: orderedValues("Name", "n", "Phone", "p1", "Mail", "m1", "Phone", "p2"),
+			expected: "Name=n Phone=p1 Mail=m1 Phone=p2"},
+		{name: "optional group reference", declarations: testGroup, values: orderedValues("Name", "n"), expected: "Name=n"},
+		{name: "group reference limited by maxOccurs", declarations: testGroup,
+			values:   orderedValues("Name", "n", "Phone", "p1", "Phone", "p2", "Phone", "p3"),
+			expected: "Name=n Phone=p1 Phone=p2"},
+		{name: "repeated sequence", declarations: testRepeatedSequence, values: orderedValues("Key", "a", "Value", "1", "Key", "b", "Value", "2"),
+			expected: "Key=a Value=1 Key=b Value=2"},
+		// occurrences of the group without values left are filled like missing elements
+		{name: "repeated sequence without values left", declarations: testRepeatedSequence, values: orderedValues("Key", "a", "Key", "b"),
+			expected: "Key=a Value= Key=b Value="},
+		{name: "required sequence without values", declarations: testRequiredSequence, values: orderedValues(),
+			expected: "A= B= A= B="},
+		{name: "unresolved group", declarations: testUnresolvedGroup, values: orderedValues(), err: ErrUnresolvedGroup},
+	})
+}
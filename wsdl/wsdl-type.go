@@ -1,6 +1,9 @@
 package wsdl
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/lordkhonsu/go-soap/dom"
 )
 
@@ -13,52 +16,217 @@ type Type struct {
 	w3cName         string
 }
 
-func (t *Type) build(parent *dom.Node, name string, namespace string, body *dom.Document, typeExtensions map[string]string) *dom.Node {
-	var self *dom.Node
+// build creates the element name in parent from its body values
+func (t *Type) build(parent *dom.Node, name string, namespace string, values *dom.Node, typeExtensions map[string]string) (*dom.Node, error) {
+	self, err := t.buildContent(parent, name, namespace, values, typeExtensions, map[*dom.Node]bool{})
+	if err != nil {
+		return nil, err
+	}
+
+	if values.Exists && !values.IsNil() {
+		values.CopyValue(self)
+	}
 
+	// attributes of this type and its base types
+	if err := t.buildAttributes(self, values, map[string]bool{}, map[*dom.Node]bool{}); err != nil {
+		return nil, err
+	}
+
+	// derived complex types are named by their QName, embedded ones have none
+	if derivation := t.derivation(); derivation.Exists && derivation.Parent.Name == "complexContent" && t.Name() != "" {
+		self.SetAttribute(xsiNS, "type", t.qualifiedName(self))
+	}
+
+	if self.Children.Len() == 0 {
+		if self.IsNil() {
+			self.SetAttribute(xsiNS, "nil", "true")
+		} else {
+			self.SetAttribute(xsiNS, "nil", "false")
+		}
+	}
+
+	return self, nil
+}
+
+// buildContent creates the element with the elements of this type, preceded by those of its base types
+// (visited holds the types of the derivation chain built so far)
+func (t *Type) buildContent(parent *dom.Node, name string, namespace string, values *dom.Node, typeExtensions map[string]string, visited map[*dom.Node]bool) (*dom.Node, error) {
 	// basic type, nothing to do here
 	if t.w3cType {
-		self = parent.NewChildren(name, namespace)
+		return parent.NewChildren(name, namespace), nil
+	}
 
-	} else {
-		// complex content -> base class
-		xPathPrefix := ""
-		if extension := t.domNode.XPath("complexContent/extension").First(); extension.Exists {
-			xPathPrefix = "complexContent/extension/"
-			baseType := t.wsdl.FindType(extension.GetAttributeValue("base"), t.domNode)
-			self = baseType.build(parent, name, namespace, body, typeExtensions)
-			self.SetAttribute("http://www.w3.org/2001/XMLSchema-instance", "type", t.domNode.GetAttributeValue("name"))
-		} else {
+	var self *dom.Node
+	content := t.domNode
+	if derivation := t.derivation(); derivation.Exists {
+		// complex or simple content -> base class
+		content = derivation
+		baseType, err := t.baseType(derivation, visited)
+		if err != nil {
+			return nil, err
+		}
+		if derivation.Name == "restriction" && derivation.Parent.Name == "complexContent" {
+			// a restriction restates the whole content model of its base
 			self = parent.NewChildren(name, namespace)
+		} else if self, err = baseType.buildContent(parent, name, namespace, values, typeExtensions, visited); err != nil {
+			return nil, err
 		}
+	} else {
+		self = parent.NewChildren(name, namespace)
+	}
 
-		// embedded complex type -> sequence of new elements
-		if sequence := t.domNode.XPath(xPathPrefix + "sequence/element"); sequence.Len() > 0 {
-			for _, domNode := range sequence.All() {
-				element := &Element{
-					wsdl:            t.wsdl,
-					targetNamespace: t.targetNamespace,
-					domNode:         domNode,
-				}
-
-				element.Build(self, body, typeExtensions)
+	// embedded complex type -> model group of new elements
+	for _, particle := range content.Children.All() {
+		if isModelGroup(particle) {
+			if err := t.wsdl.buildParticle(particle, t.targetNamespace, self, values, typeExtensions); err != nil {
+				return nil, err
 			}
 		}
 	}
+	return self, nil
+}
+
+// buildAttributes sets the attributes declared by this type and its base types, the most derived declaration wins
+// (visited holds the types of the derivation chain walked so far)
+func (t *Type) buildAttributes(self *dom.Node, values *dom.Node, declared map[string]bool, visited map[*dom.Node]bool) error {
+	if t.w3cType {
+		return nil
+	}
 
-	if val := body.XPath(self.GetXPath()).First(); val.Exists && !val.IsNil() {
-		val.CopyValue(self)
+	derivation := t.derivation()
+	content := t.domNode
+	if derivation.Exists {
+		content = derivation
+	}
+	if err := t.wsdl.buildAttributes(content, t.targetNamespace, self, values, declared); err != nil {
+		return err
 	}
 
-	if self.Children.Len() == 0 {
-		if self.IsNil() {
-			self.SetAttribute("http://www.w3.org/2001/XMLSchema-instance", "nil", "true")
+	if !derivation.Exists {
+		return nil
+	}
+	baseType, err := t.baseType(derivation, visited)
+	if err != nil {
+		return err
+	}
+	return baseType.buildAttributes(self, values, declared, visited)
+}
+
+// derivation returns the <extension> or <restriction> this type is derived by, if any
+func (t *Type) derivation() *dom.Node {
+	if t.w3cType {
+		return &dom.Node{}
+	}
+	return t.domNode.XPath("complexContent/extension | complexContent/restriction | simpleContent/extension | simpleContent/restriction").First()
+}
+
+// baseType returns the type this type is derived from, failing if the base is final for the derivation or was
+// visited before in the derivation chain
+func (t *Type) baseType(derivation *dom.Node, visited map[*dom.Node]bool) (*Type, error) {
+	visited[t.domNode] = true
+	baseType, err := t.wsdl.LookupType(derivation.GetAttributeValue("base"), derivation)
+	if err != nil {
+		return nil, err
+	}
+	if !baseType.w3cType && visited[baseType.domNode] {
+		return nil, fmt.Errorf("%w: type [%s] is derived from itself", ErrInvalidWSDL, baseType.Name())
+	}
+	if baseType.derivationControl("final", "finalDefault")[derivation.Name] {
+		return nil, fmt.Errorf("%w: type [%s] derives by %s from final type [%s]", ErrInvalidWSDL, t.Name(),
+			derivation.Name, baseType.Name())
+	}
+	return baseType, nil
+}
+
+// derivationMethods returns the methods (extension, restriction) this type is derived by from base,
+// false if it is not derived from base at all
+func (t *Type) derivationMethods(base *Type) ([]string, bool, error) {
+	methods := []string{}
+	visited := map[*dom.Node]bool{}
+	for current := t; !current.is(base); {
+		derivation := current.derivation()
+		if !derivation.Exists {
+			return nil, false, nil
+		}
+		visited[current.domNode] = true
+		next, err := current.wsdl.LookupType(derivation.GetAttributeValue("base"), derivation)
+		if err != nil {
+			return nil, false, nil
+		}
+		if !next.w3cType && visited[next.domNode] {
+			return nil, false, fmt.Errorf("%w: type [%s] is derived from itself", ErrInvalidWSDL, next.Name())
+		}
+		methods = append(methods, derivation.Name)
+		current = next
+	}
+	return methods, true, nil
+}
+
+// derivationControl returns the methods listed by the final or block (kind) attribute of this type,
+// falling back to finalDefault or blockDefault (schemaDefault) of its <schema>
+func (t *Type) derivationControl(kind string, schemaDefault string) map[string]bool {
+	if t.w3cType {
+		return map[string]bool{}
+	}
+	return derivationControl(t.domNode, kind, schemaDefault)
+}
+
+// derivationControl returns the methods listed by the final or block (kind) attribute of a declaration,
+// falling back to finalDefault or blockDefault (schemaDefault) of its <schema>
+func derivationControl(declaration *dom.Node, kind string, schemaDefault string) map[string]bool {
+	value, exists := declaration.GetAttribute(kind)
+	if !exists {
+		for node := declaration.Parent; node != nil; node = node.Parent {
+			if node.Name == "schema" {
+				value, exists = node.GetAttribute(schemaDefault)
+				break
+			}
+		}
+	}
+
+	result := map[string]bool{}
+	if !exists {
+		return result
+	}
+	for _, method := range strings.Fields(value.Value) {
+		if method == "#all" {
+			result["extension"] = true
+			result["restriction"] = true
+			result["substitution"] = true
 		} else {
-			self.SetAttribute("http://www.w3.org/2001/XMLSchema-instance", "nil", "false")
+			result[method] = true
 		}
 	}
+	return result
+}
+
+// Name returns the name of this type, empty for embedded types
+func (t *Type) Name() string {
+	if t.w3cType {
+		return t.w3cName
+	}
+	return t.domNode.GetAttributeValue("name")
+}
+
+// IsAbstract returns true if this type may only be used by its derived types
+func (t *Type) IsAbstract() bool {
+	return !t.w3cType && t.domNode.GetAttributeValue("abstract") == "true"
+}
 
-	return self
+// is returns true if both describe the same type
+func (t *Type) is(other *Type) bool {
+	if t.w3cType || other.w3cType {
+		return t.w3cType == other.w3cType && t.w3cName == other.w3cName
+	}
+	return t.domNode == other.domNode
+}
+
+// qualifiedName returns the QName of this type, prefixed as declared in scope of node
+func (t *Type) qualifiedName(node *dom.Node) string {
+	if namespace := node.ResolveNS(t.targetNamespace); namespace != nil && namespace.Abbreviation != "" {
+		return namespace.Abbreviation + ":" + t.Name()
+	}
+	return t.Name()
 }
 
 func (t *Type) debug() string {
@@ -0,0 +1,186 @@
+package wsdl
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lordkhonsu/go-soap/dom"
+)
+
+const testDerivedTypes = `<xs:complexType name="Animal" abstract="true"><xs:sequence>
+      <xs:element name="Name" type="xs:string"/>
+    </xs:sequence></xs:complexType>
+    <xs:complexType name="Dog"><xs:complexContent><xs:extension base="tns:Animal"><xs:sequence>
+      <xs:element name="Breed" type="xs:string"/>
+    </xs:sequence></xs:extension></xs:complexContent></xs:complexType>
+    <xs:complexType name="Puppy"><xs:complexContent><xs:extension base="tns:Dog"><xs:sequence>
+      <xs:element name="Age" type="xs:int"/>
+    </xs:sequence></xs:extension></xs:complexContent></xs:complexType>
+    <xs:complexType name="NamedDog"><xs:complexContent><xs:restriction base="tns:Dog"><xs:sequence>
+      <xs:element name="Name" type="xs:string"/>
+    </xs:sequence></xs:restriction></xs:complexContent></xs:complexType>
+    <xs:complexType name="Cat"><xs:sequence>
+      <xs:element name="Name" type="xs:string"/>
+    </xs:sequence></xs:complexType>
+    <xs:complexType name="Sealed" final="extension"><xs:sequence>
+      <xs:element name="Name" type="xs:string"/>
+    </xs:sequence></xs:complexType>
+    <xs:complexType name="Unsealed"><xs:complexContent><xs:extension base="tns:Sealed"/></xs:complexContent></xs:complexType>
+    <xs:complexType name="Chicken"><xs:complexContent><xs:extension base="tns:Egg"/></xs:complexContent></xs:complexType>
+    <xs:complexType name="Egg"><xs:complexContent><xs:extension base="tns:Chicken"/></xs:complexContent></xs:complexType>
+    <xs:element name="Request"><xs:complexType><xs:sequence>
+      <xs:element name="Pet" type="tns:Animal" minOccurs="0"/>
+      <xs:element name="Guard" type="tns:Dog" minOccurs="0" block="restriction"/>
+      <xs:element name="Sealed" type="tns:Unsealed" minOccurs="0"/>
+      <xs:element name="Egg" type="tns:Egg" minOccurs="0"/>
+      <xs:element name="Stray" minOccurs="0"><xs:complexType><xs:complexContent><xs:extension base="tns:Cat"><xs:sequence>
+        <xs:element name="Owner" type="xs:string"/>
+      </xs:sequence></xs:extension></xs:complexContent></xs:complexType></xs:element>
+    </xs:sequence></xs:complexType></xs:element>`
+
+// describeTypes lists the children built into the request element as name(xsi:type: elements), the xsi:type
+// resolved to its namespace
+func describeTypes(envelope *dom.Document) string {
+	result := []string{}
+	for _, child := range envelope.Root.XPath("Body/Request/*").All() {
+		description := child.Name + "("
+		if attr, exists := child.GetAttributeNS(xsiNS, "type"); exists {
+			prefix, local := dom.SplitFQName(attr.Value)
+			if namespace := child.ResolveNSAbbrev(prefix); namespace != nil && namespace.Name == testNamespace {
+				description += "tns:"
+			}
+			description += local + ":"
+		}
+		for _, element := range child.Children.All() {
+			description += " " + element.Name
+		}
+		result = append(result, description+")")
+	}
+	return strings.Join(result, " ")
+}
+
+func TestBuildDerivedTypes(t *testing.T) {
+	client := newTestClient(t, testSchema(`xmlns:tns="`+testNamespace+`"`, testDerivedTypes))
+	pet := "/s:Envelope/Body[1]/Request[1]/Pet[1]"
+	guard := "/s:Envelope/Body[1]/Request[1]/Guard[1]"
+
+	tests := []struct {
+		name       string
+		values     *dom.Document
+		extensions map[string]string
+		expected   string
+		err        error
+	}{
+		{"extension", orderedValues("Pet", ""), map[string]string{pet: "tns:Dog"}, "Pet(tns:Dog: Name Breed)", nil},
+		{"multi-level extension", orderedValues("Pet", ""), map[string]string{pet: "tns:Puppy"}, "Pet(tns:Puppy: Name Breed Age)", nil},
+		{"restriction", orderedValues("Pet", ""), map[string]string{pet: "tns:NamedDog"}, "Pet(tns:NamedDog: Name)", nil},
+		{"declared type", orderedValues("Guard", ""), nil, "Guard(tns:Dog: Name Breed)", nil},
+		{"embedded type", orderedValues("Stray", ""), nil, "Stray( Name Owner)", nil},
+		{"abstract type", orderedValues("Pet", ""), nil, "", ErrInvalidBody},
+		{"not derived", orderedValues("Pet", ""), map[string]string{pet: "tns:Cat"}, "", ErrInvalidBody},
+		{"blocked restriction", orderedValues("Guard", ""), map[string]string{guard: "tns:NamedDog"}, "", ErrInvalidBody},
+		{"final base", orderedValues("Sealed", ""), nil, "", ErrInvalidWSDL},
+		{"cyclic derivation", orderedValues("Egg", ""), nil, "", ErrInvalidWSDL},
+		{"cyclic type extension", orderedValues("Pet", ""), map[string]string{pet: "tns:Chicken"}, "", ErrInvalidWSDL},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			envelope, err := buildTestBody(t, client, test.values, test.extensions)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("expected [%v], got [%v]", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result := describeTypes(envelope); result != test.expected {
+				t.Fatalf("expected [%s], got [%s]", test.expected, result)
+			}
+		})
+	}
+}
+
+const testSimpleContent = `<xs:complexType name="Money"><xs:simpleContent><xs:extension base="xs:decimal">
+      <xs:attribute name="currency" type="xs:string" use="required"/>
+      <xs:attribute name="rate" type="xs:decimal" default="1"/>
+    </xs:extension></xs:simpleContent></xs:complexType>
+    <xs:complexType name="Euro"><xs:simpleContent><xs:restriction base="tns:Money">
+      <xs:attribute name="currency" type="xs:string" fixed="EUR"/>
+      <xs:attribute name="rate" type="xs:decimal" use="prohibited"/>
+    </xs:restriction></xs:simpleContent></xs:complexType>
+    <xs:element name="Request"><xs:complexType><xs:sequence>
+      <xs:element name="Amount" type="tns:Money" minOccurs="0"/>
+      <xs:element name="Price" type="tns:Euro" minOccurs="0"/>
+    </xs:sequence></xs:complexType></xs:element>`
+
+// describeContent lists the children built into the request element as name(attributes)=value, except xsi:*
+func describeContent(envelope *dom.Document) string {
+	result := []string{}
+	for _, child := range envelope.Root.XPath("Body/Request/*").All() {
+		attributes := []string{}
+		for _, attr := range child.Attributes {
+			if !attr.InNamespace(xsiNS) {
+				attributes = append(attributes, attr.Name+"="+attr.Value)
+			}
+		}
+		result = append(result, child.Name+"("+strings.Join(attributes, " ")+")="+child.String())
+	}
+	return strings.Join(result, " ")
+}
+
+func TestBuildSimpleContent(t *testing.T) {
+	client := newTestClient(t, testSchema(`xmlns:tns="`+testNamespace+`"`, testSimpleContent))
+
+	tests := []struct {
+		name     string
+		values   dom.Map
+		expected string
+		err      error
+	}{
+		{"extension", dom.Map{"Amount": dom.Map{"@currency": "USD", "@rate": "0.9", "#text": 12.5}},
+			"Amount(currency=USD rate=0.9)=12.5", nil},
+		{"extension with default", dom.Map{"Amount": dom.Map{"@currency": "USD", "#text": 12.5}},
+			"Amount(currency=USD rate=1)=12.5", nil},
+		{"extension without required attribute", dom.Map{"Amount": dom.Map{"#text": 12.5}}, "", ErrInvalidBody},
+		{"restriction", dom.Map{"Price": dom.Map{"#text": 3}}, "Price(currency=EUR)=3", nil},
+		{"restriction prohibiting an attribute", dom.Map{"Price": dom.Map{"@rate": "2", "#text": 3}}, "Price(currency=EUR)=3", nil},
+		{"restriction with fixed mismatch", dom.Map{"Price": dom.Map{"@currency": "USD", "#text": 3}}, "", ErrInvalidBody},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			envelope, err := buildTestBody(t, client, dom.Convert("Request", test.values), nil)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("expected [%v], got [%v]", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result := describeContent(envelope); result != test.expected {
+				t.Fatalf("expected [%s], got [%s]", test.expected, result)
+			}
+
+			// simple content is no derivation by xsi:type, and reads back as value along with the attributes
+			element := envelope.Root.XPath("Body/Request/*").First()
+			if _, exists := element.GetAttributeNS(xsiNS, "type"); exists {
+				t.Fatal("expected no xsi:type")
+			}
+			mapping, _ := element.Parent.ToMap()[element.Name].(dom.Map)
+			expected := dom.Map{"#text": element.String()}
+			for _, attr := range element.Attributes {
+				if !attr.InNamespace(xsiNS) {
+					expected["@"+attr.Name] = attr.Value
+				}
+			}
+			if !reflect.DeepEqual(mapping, expected) {
+				t.Fatalf("expected %v, got %v", expected, mapping)
+			}
+		})
+	}
+}
@@ -1,13 +1,23 @@
 package wsdl
 
 import (
-	"fmt"
 	"github.com/lordkhonsu/go-soap/dom"
 )
 
 // WSDL wraps a WSDL specific dom.Document and provides helper methods
 type WSDL struct {
 	document *dom.Document
+
+	// all <definitions> (including wsdl:import) and all <schema> (including xsd:import and xsd:include)
+	definitions []*dom.Node
+	schemas     map[string][]*dom.Node
+}
+
+func newWSDL(document *dom.Document) *WSDL {
+	return &WSDL{
+		document: document,
+		schemas:  map[string][]*dom.Node{},
+	}
 }
 
 // XPath resolves the given XPath to a list of Nodes
@@ -15,35 +25,108 @@ func (w *WSDL) XPath(xpath string, arguments ...interface{}) *dom.NodeList {
 	return w.document.XPath(xpath, arguments...)
 }
 
+// findDefinition finds the named definition (<service>, <binding>, <portType>, <message>) in all WSDL documents
+func (w *WSDL) findDefinition(kind string, name string) *dom.Node {
+	for _, definitions := range w.definitions {
+		if node := definitions.XPath("%s[@name='%s']", kind, name).First(); node.Exists {
+			return node
+		}
+	}
+	return &dom.Node{}
+}
+
+// allDefinitions returns all definitions of the given kind in all WSDL documents
+func (w *WSDL) allDefinitions(kind string) *dom.NodeList {
+	result := &dom.NodeList{}
+	for _, definitions := range w.definitions {
+		result.AppendList(definitions.XPath("%s", kind))
+	}
+	return result
+}
+
+// findSchemaChild finds the named top-level schema component (<element>, <complexType>, ...) in the given namespace
+func (w *WSDL) findSchemaChild(namespace string, kind string, name string) *dom.Node {
+	for _, schema := range w.schemas[namespace] {
+		if node := schema.XPath("%s[@name='%s']", kind, name).First(); node.Exists {
+			return node
+		}
+	}
+	return &dom.Node{}
+}
+
+// lookupComponent finds the named top-level <group>, <attributeGroup> or <attribute> (kind), returning it along with its namespace
+func (w *WSDL) lookupComponent(kind string, fqName string, relative *dom.Node) (*dom.Node, string, error) {
+	errorKind := ErrUnresolvedGroup
+	if kind == "attribute" {
+		errorKind = ErrUnresolvedAttribute
+	}
+
+	componentNS, componentName := dom.SplitFQName(fqName)
+	namespace, err := relative.LookupNSAbbrev(componentNS)
+	if err != nil {
+		return nil, "", &ResolveError{Kind: errorKind, FQName: fqName, Err: err}
+	}
+
+	componentNode := w.findSchemaChild(namespace.Name, kind, componentName)
+	if !componentNode.Exists {
+		return nil, "", &ResolveError{Kind: errorKind, FQName: fqName, Namespace: namespace.Name}
+	}
+	return componentNode, namespace.Name, nil
+}
+
 // FindElement finds the specified <element> in the WSDL and returns a dom.Node that represents it
 func (w *WSDL) FindElement(fqName string, relative *dom.Node) *Element {
+	element, err := w.LookupElement(fqName, relative)
+	if err != nil {
+		panic(err)
+	}
+	return element
+}
+
+// LookupElement finds the specified <element> in the WSDL, returning a ResolveError if it cannot be found
+func (w *WSDL) LookupElement(fqName string, relative *dom.Node) (*Element, error) {
 	elemNS, elemName := dom.SplitFQName(fqName)
 	base := w.document.Root
 	if relative != nil {
 		base = relative
 	}
-	namespace := base.ResolveNSAbbrev(elemNS)
+	namespace, err := base.LookupNSAbbrev(elemNS)
+	if err != nil {
+		return nil, &ResolveError{Kind: ErrUnresolvedElement, FQName: fqName, Err: err}
+	}
 
-	elemNode := w.XPath("/definitions/types/schema[@targetNamespace='%s']/element[@name='%s']", namespace.Name, elemName).First()
+	elemNode := w.findSchemaChild(namespace.Name, "element", elemName)
 	if !elemNode.Exists {
-		panic(fmt.Errorf("FindElement(): element with fqName [%s] not found", fqName))
+		return nil, &ResolveError{Kind: ErrUnresolvedElement, FQName: fqName, Namespace: namespace.Name}
 	}
 
 	return &Element{
 		wsdl:            w,
 		targetNamespace: namespace.Name,
 		domNode:         elemNode,
-	}
+	}, nil
 }
 
 // FindType finds the specified <type> in the WSDL and returns a dom.Node that represents it
 func (w *WSDL) FindType(fqName string, relative *dom.Node) *Type {
+	t, err := w.LookupType(fqName, relative)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// LookupType finds the specified <type> in the WSDL, returning a ResolveError if it cannot be found
+func (w *WSDL) LookupType(fqName string, relative *dom.Node) (*Type, error) {
 	elemNS, elemName := dom.SplitFQName(fqName)
 	base := w.document.Root
 	if relative != nil {
 		base = relative
 	}
-	namespace := base.ResolveNSAbbrev(elemNS)
+	namespace, err := base.LookupNSAbbrev(elemNS)
+	if err != nil {
+		return nil, &ResolveError{Kind: ErrUnresolvedType, FQName: fqName, Err: err}
+	}
 
 	if namespace.Name == "http://www.w3.org/2001/XMLSchema" {
 		return &Type{
@@ -51,27 +134,25 @@ func (w *WSDL) FindType(fqName string, relative *dom.Node) *Type {
 			targetNamespace: namespace.Name,
 			w3cType:         true,
 			w3cName:         elemName,
-		}
+		}, nil
 	}
 
-	baseSchema := w.XPath("/definitions/types/schema[@targetNamespace='%s']", namespace.Name).First()
-
 	// find complexType first
-	elemNode := baseSchema.XPath("complexType[@name='%s']", elemName).First()
+	elemNode := w.findSchemaChild(namespace.Name, "complexType", elemName)
 
 	// find simpleType next
 	if !elemNode.Exists {
-		elemNode = baseSchema.XPath("simpleType[@name='%s']", elemName).First()
+		elemNode = w.findSchemaChild(namespace.Name, "simpleType", elemName)
 	}
 
 	// nothing found
 	if !elemNode.Exists {
-		panic(fmt.Errorf("FindType(): type with fqName [%s] not found in ns [%s]", fqName, namespace.Name))
+		return nil, &ResolveError{Kind: ErrUnresolvedType, FQName: fqName, Namespace: namespace.Name}
 	}
 
 	return &Type{
 		wsdl:            w,
 		targetNamespace: namespace.Name,
 		domNode:         elemNode,
-	}
+	}, nil
 }
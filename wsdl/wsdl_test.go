@@ -0,0 +1,117 @@
+package wsdl
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/lordkhonsu/go-soap/dom"
+)
+
+const testNamespace = "http://example.com/test"
+
+// testDefinitions returns a WSDL with the operation "Call" of "TestService", sending the element tns:Request.
+// The schemas are placed into <types> as given
+func testDefinitions(schemas string) string {
+	return `<?xml version="1.0" encoding="utf-8"?>
+<wsdl:definitions name="Test" targetNamespace="` + testNamespace + `"
+  xmlns:wsdl="http://schemas.xmlsoap.org/wsdl/"
+  xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+  xmlns:xs="http://www.w3.org/2001/XMLSchema"
+  xmlns:tns="` + testNamespace + `">
+  <wsdl:types>` + schemas + `</wsdl:types>
+  <wsdl:message name="CallIn"><wsdl:part name="parameters" element="tns:Request"/></wsdl:message>
+  <wsdl:message name="CallOut"><wsdl:part name="parameters" element="tns:Response"/></wsdl:message>
+  <wsdl:portType name="TestPort">
+    <wsdl:operation name="Call">
+      <wsdl:input message="tns:CallIn"/>
+      <wsdl:output message="tns:CallOut"/>
+    </wsdl:operation>
+  </wsdl:portType>
+  <wsdl:binding name="TestBinding" type="tns:TestPort">
+    <soap:binding transport="http://schemas.xmlsoap.org/soap/http"/>
+    <wsdl:operation name="Call">
+      <soap:operation soapAction="` + testNamespace + `/Call"/>
+      <wsdl:input><soap:body use="literal"/></wsdl:input>
+      <wsdl:output><soap:body use="literal"/></wsdl:output>
+    </wsdl:operation>
+  </wsdl:binding>
+  <wsdl:service name="TestService">
+    <wsdl:port name="TestPort" binding="tns:TestBinding">
+      <soap:address location="http://localhost/test"/>
+    </wsdl:port>
+  </wsdl:service>
+</wsdl:definitions>`
+}
+
+// testSchema wraps the declarations into a schema of the test namespace, declaring tns:Response
+func testSchema(attributes string, declarations string) string {
+	return `<xs:schema targetNamespace="` + testNamespace + `" ` + attributes + `>
+    <xs:element name="Response" type="xs:string"/>` + declarations + `
+  </xs:schema>`
+}
+
+func newTestClient(t testing.TB, schemas string, options ...Option) *Client {
+	t.Helper()
+	client, err := NewClientFromReader(strings.NewReader(testDefinitions(schemas)), options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func newTestRequest(t testing.TB, client *Client) *Request {
+	t.Helper()
+	operation, err := client.Service("TestService").LookupOperation("Call")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return operation.NewRequest()
+}
+
+// buildTestBody builds the request body for the given values, returning the parsed envelope
+func buildTestBody(t *testing.T, client *Client, values *dom.Document, typeExtensions map[string]string) (*dom.Document, error) {
+	t.Helper()
+	request := newTestRequest(t, client)
+	if values != nil {
+		request.SetBodyValues(values)
+	}
+	for xpath, fqType := range typeExtensions {
+		request.SetTypeExtension(xpath, fqType)
+	}
+	data, err := request.XMLE()
+	if err != nil {
+		return nil, err
+	}
+	envelope, err := dom.Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("request is no well-formed XML: %v\n%s", err, data)
+	}
+	return envelope, nil
+}
+
+// testTransport records the requests sent and answers them with respond, an empty 200 response if respond is nil
+type testTransport struct {
+	requests []*TransportRequest
+	respond  func(ctx context.Context, request *TransportRequest) (*TransportResponse, error)
+}
+
+func (t *testTransport) RoundTrip(ctx context.Context, request *TransportRequest) (*TransportResponse, error) {
+	t.requests = append(t.requests, request)
+	if t.respond == nil {
+		return &TransportResponse{StatusCode: 200}, nil
+	}
+	return t.respond(ctx, request)
+}
+
+// respondWith returns a testTransport answering every request with the given status, content type and body
+func respondWith(statusCode int, contentType string, body string) *testTransport {
+	return &testTransport{respond: func(ctx context.Context, request *TransportRequest) (*TransportResponse, error) {
+		return &TransportResponse{
+			StatusCode: statusCode,
+			Header:     http.Header{"Content-Type": []string{contentType}},
+			Envelope:   []byte(body),
+		}, nil
+	}}
+}